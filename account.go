@@ -0,0 +1,333 @@
+package msops
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Account represents a MySQL account, i.e. 'user'@'host'.
+type Account struct {
+	User string
+	Host string
+}
+
+// PrivilegeReport lists the global privileges missing for the registered users of an endpoint.
+//
+// ReplUserError is the error of connecting as the replUser, whose privileges are unknown if it's not nil.
+type PrivilegeReport struct {
+	DBAUserMissing  []string
+	ReplUserMissing []string
+	ReplUserError   error
+}
+
+var (
+	// dbaPrivileges and replPrivileges are the global privileges required by the registered users.
+	dbaPrivileges  = []string{"RELOAD", "PROCESS", "SUPER", "REPLICATION CLIENT", "REPLICATION SLAVE"}
+	replPrivileges = []string{"REPLICATION SLAVE"}
+
+	privilegeExp  = regexp.MustCompile(`^[A-Z][A-Z_ ]*$`)
+	grantLevelExp = regexp.MustCompile("^(\\*|`[^`]+`|[0-9a-zA-Z_$]+)\\.(\\*|`[^`]+`|[0-9a-zA-Z_$]+)$")
+	grantExp      = regexp.MustCompile(`^GRANT (.+) ON (\S+) TO `)
+
+	errAccountInvalid   = errors.New("the user of the account should not be empty")
+	errAccountBackslash = errors.New("the user and host of the account should not contain backslashes")
+	errPrivilegeInvalid = errors.New("the privilege is not valid")
+	errGrantLevel       = errors.New("the privilege level should be in the form db.table")
+)
+
+// String returns the account in the form 'user'@'host', where host is '%' if it's empty.
+// The accounts with backslashes are rejected by the operations, since their quoted form depends on sql_mode.
+func (account Account) String() string {
+	host := account.Host
+	if host == "" {
+		host = "%"
+	}
+	return quoteString(account.User) + "@" + quoteString(host)
+}
+
+// validate checks that the user isn't empty and that the account can be quoted by String under any sql_mode.
+func (account Account) validate() error {
+	if account.User == "" {
+		return errAccountInvalid
+	}
+	return account.validateQuoting()
+}
+
+// validateQuoting checks that the account has no backslash, which is escaped by String
+// but read literally when NO_BACKSLASH_ESCAPES is in sql_mode.
+func (account Account) validateQuoting() error {
+	if strings.Contains(account.User, `\`) || strings.Contains(account.Host, `\`) {
+		return errAccountBackslash
+	}
+	return nil
+}
+
+// OK reports whether no privilege is missing.
+func (report PrivilegeReport) OK() bool {
+	return len(report.DBAUserMissing) == 0 && len(report.ReplUserMissing) == 0 && report.ReplUserError == nil
+}
+
+// CreateUser creates the account identified by password at the endpoint.
+func CreateUser(endpoint string, account Account, password string) error {
+	return createUser(endpoint, account, secret{value: password})
+}
+
+func createUser(endpoint string, account Account, password secret) error {
+	if err := account.validate(); err != nil {
+		return err
+	}
+	return execute(endpoint, "CREATE USER "+account.String()+" IDENTIFIED BY ?", password)
+}
+
+// AlterUserPassword changes the password of the account at the endpoint.
+// "SET PASSWORD" is used before MySQL 5.7.6.
+func AlterUserPassword(endpoint string, account Account, password string) error {
+	return alterUserPassword(endpoint, account, secret{value: password})
+}
+
+func alterUserPassword(endpoint string, account Account, password secret) error {
+	if err := account.validate(); err != nil {
+		return err
+	}
+	version, err := getVersion(endpoint)
+	if err != nil {
+		return err
+	}
+	if !version.atLeast(5, 7, 6) {
+		return execute(endpoint, "SET PASSWORD FOR "+account.String()+" = PASSWORD(?)", password)
+	}
+	return execute(endpoint, "ALTER USER "+account.String()+" IDENTIFIED BY ?", password)
+}
+
+// DropUser drops the account at the endpoint.
+func DropUser(endpoint string, account Account) error {
+	if err := account.validate(); err != nil {
+		return err
+	}
+	return execute(endpoint, "DROP USER "+account.String())
+}
+
+// Grant grants the privileges on the level, such as "*.*" or "db.*", to the account at the endpoint.
+func Grant(endpoint string, account Account, privileges []string, level string) error {
+	clause, err := privilegeClause(account, privileges, level)
+	if err != nil {
+		return err
+	}
+	return execute(endpoint, "GRANT "+clause+" TO "+account.String())
+}
+
+// Revoke revokes the privileges on the level, such as "*.*" or "db.*", from the account at the endpoint.
+func Revoke(endpoint string, account Account, privileges []string, level string) error {
+	clause, err := privilegeClause(account, privileges, level)
+	if err != nil {
+		return err
+	}
+	return execute(endpoint, "REVOKE "+clause+" FROM "+account.String())
+}
+
+// privilegeClause validates and builds "privileges ON level".
+func privilegeClause(account Account, privileges []string, level string) (string, error) {
+	if err := account.validate(); err != nil {
+		return "", err
+	}
+	if len(privileges) == 0 {
+		return "", errPrivilegeInvalid
+	}
+	normalized := make([]string, len(privileges))
+	for i, privilege := range privileges {
+		normalized[i] = strings.ToUpper(strings.TrimSpace(privilege))
+		if !privilegeExp.MatchString(normalized[i]) {
+			return "", errPrivilegeInvalid
+		}
+	}
+	if !grantLevelExp.MatchString(level) {
+		return "", errGrantLevel
+	}
+	return strings.Join(normalized, ", ") + " ON " + level, nil
+}
+
+// GetGrants executes "SHOW GRANTS FOR account" at the endpoint.
+// The user of the account may be empty for the anonymous accounts.
+func GetGrants(endpoint string, account Account) ([]string, error) {
+	if err := account.validateQuoting(); err != nil {
+		return nil, err
+	}
+	dataSet, err := readDataSet(endpoint, "SHOW GRANTS FOR "+account.String())
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]string, 0, len(dataSet))
+	for _, row := range dataSet {
+		for _, grant := range row {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+// VerifyPrivileges checks that the registered dbaUser and replUser of the endpoint have the global privileges
+// required by msops, by parsing "SHOW GRANTS" of each user.
+func VerifyPrivileges(endpoint string) (PrivilegeReport, error) {
+	var report PrivilegeReport
+	var inst *Instance
+	var exists bool
+	if inst, exists = getInstance(endpoint); !exists {
+		return report, errNotRegistered
+	}
+	grants, err := readGrants(inst.connection)
+	if err != nil {
+		return report, err
+	}
+	report.DBAUserMissing = missingPrivileges(grants, dbaPrivileges)
+
+	if grants, report.ReplUserError = readReplUserGrants(endpoint, inst); report.ReplUserError == nil {
+		report.ReplUserMissing = missingPrivileges(grants, replPrivileges)
+	}
+	return report, nil
+}
+
+// EnsureReplicationUser provisions the registered replUser of the master idempotently:
+// the account 'replUser'@'%' is created if not exists, REPLICATION SLAVE is granted if missing,
+// and the password is reset if the replUser can't log in with the registered replPassword.
+//
+// The statements are replicated to the slaves of the master, provisioning the user across the cluster.
+// Since MySQL 5.7.6 "CREATE USER IF NOT EXISTS" is used, so that the slaves already having the account don't fail.
+// Before that, it refuses to create the account if any registered slave of the master already has it.
+func EnsureReplicationUser(masterEndpoint string) error {
+	var inst *Instance
+	var exists bool
+	if inst, exists = getInstance(masterEndpoint); !exists {
+		return errNotRegistered
+	}
+	version, err := getVersion(masterEndpoint)
+	if err != nil {
+		return err
+	}
+	account := Account{User: inst.replUser, Host: "%"}
+	grants, err := GetGrants(masterEndpoint, account)
+	if isNoSuchGrantError(err) {
+		if version.atLeast(5, 7, 6) {
+			err = execute(masterEndpoint, "CREATE USER IF NOT EXISTS "+account.String()+" IDENTIFIED BY ?",
+				replPasswordOf(masterEndpoint, inst))
+		} else if err = checkSlavesWithoutAccount(masterEndpoint, account); err == nil {
+			err = createUser(masterEndpoint, account, replPasswordOf(masterEndpoint, inst))
+		}
+		if err != nil {
+			return err
+		}
+		return Grant(masterEndpoint, account, replPrivileges, "*.*")
+	} else if err != nil {
+		return err
+	}
+	if missing := missingPrivileges(grants, replPrivileges); len(missing) > 0 {
+		if err = Grant(masterEndpoint, account, missing, "*.*"); err != nil {
+			return err
+		}
+	}
+
+	conn, err := sql.Open(driverName, connectionString(masterEndpoint, inst.replUser, inst.replPassword, inst.connectParams))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.Ping(); isAccessDeniedError(err) {
+		return alterUserPassword(masterEndpoint, account, replPasswordOf(masterEndpoint, inst))
+	}
+	return err
+}
+
+// checkSlavesWithoutAccount returns an error if any registered slave of the master has the account,
+// where the replicated "CREATE USER" would fail with ER_CANNOT_USER.
+func checkSlavesWithoutAccount(masterEndpoint string, account Account) error {
+	masterVars, err := GetGlobalVariables(masterEndpoint, "server_uuid")
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range registeredEndpoints() {
+		if endpoint == masterEndpoint {
+			continue
+		}
+		slaveStatus, err := GetSlaveStatus(endpoint)
+		if err != nil {
+			return fmt.Errorf("can't check slave %s: %s", endpoint, err.Error())
+		}
+		matched, err := isReplicaOf(slaveStatus, masterEndpoint, masterVars["server_uuid"])
+		if err != nil {
+			return fmt.Errorf("can't check slave %s: %s", endpoint, err.Error())
+		}
+		if !matched {
+			continue
+		}
+		if _, err = GetGrants(endpoint, account); err == nil {
+			return fmt.Errorf("the account %s already exists on slave %s", account.String(), endpoint)
+		} else if !isNoSuchGrantError(err) {
+			return fmt.Errorf("can't check slave %s: %s", endpoint, err.Error())
+		}
+	}
+	return nil
+}
+
+// readReplUserGrants connects to the endpoint as the replUser of inst and reads its grants.
+func readReplUserGrants(endpoint string, inst *Instance) ([]string, error) {
+	conn, err := sql.Open(driverName, connectionString(endpoint, inst.replUser, inst.replPassword, inst.connectParams))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return readGrants(conn)
+}
+
+// readGrants executes "SHOW GRANTS" for the current user of conn.
+func readGrants(conn *sql.DB) ([]string, error) {
+	rows, err := conn.Query("SHOW GRANTS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var grants []string
+	var grant string
+	for rows.Next() {
+		if err = rows.Scan(&grant); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// missingPrivileges returns the required privileges not granted on *.* by the grants.
+func missingPrivileges(grants []string, required []string) []string {
+	granted := make(map[string]bool)
+	for _, grant := range grants {
+		matches := grantExp.FindStringSubmatch(grant)
+		if len(matches) != 3 || matches[2] != "*.*" {
+			continue
+		}
+		for _, privilege := range strings.Split(matches[1], ",") {
+			granted[strings.ToUpper(strings.TrimSpace(privilege))] = true
+		}
+	}
+	if granted["ALL PRIVILEGES"] || granted["ALL"] {
+		return nil
+	}
+	var missing []string
+	for _, privilege := range required {
+		if !granted[privilege] {
+			missing = append(missing, privilege)
+		}
+	}
+	return missing
+}
+
+// isNoSuchGrantError reports whether err is ER_NONEXISTING_GRANT, returned by "SHOW GRANTS" for an unknown account.
+func isNoSuchGrantError(err error) bool {
+	return isMySQLError(err, 1141)
+}
+
+// isAccessDeniedError reports whether err is ER_ACCESS_DENIED_ERROR.
+func isAccessDeniedError(err error) bool {
+	return isMySQLError(err, 1045)
+}
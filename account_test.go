@@ -0,0 +1,137 @@
+package msops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccountString(t *testing.T) {
+	if s := (Account{User: "o'neil"}).String(); s != "'o''neil'@'%'" {
+		t.Errorf("Test Account.String failed: actual %s, expected 'o''neil'@'%%'", s)
+	}
+	if s := (Account{User: "app", Host: "10.0.%"}).String(); s != "'app'@'10.0.%'" {
+		t.Errorf("Test Account.String failed: actual %s, expected 'app'@'10.0.%%'", s)
+	}
+}
+
+func TestMissingPrivileges(t *testing.T) {
+	grants := []string{
+		"GRANT PROCESS, REPLICATION SLAVE ON *.* TO 'repl'@'%' IDENTIFIED BY PASSWORD '*A424E797037BF97C19A2E88CF7891C5C2038C039'",
+		"GRANT SELECT, RELOAD ON `data_test`.* TO 'repl'@'%'",
+	}
+	if missing := missingPrivileges(grants, replPrivileges); len(missing) != 0 {
+		t.Errorf("Test missingPrivileges failed: actual %v, expected none", missing)
+	}
+	if missing := missingPrivileges(grants, []string{"RELOAD", "PROCESS"}); !reflect.DeepEqual(missing, []string{"RELOAD"}) {
+		t.Errorf("Test missingPrivileges failed: actual %v, expected [RELOAD]", missing)
+	}
+	if missing := missingPrivileges([]string{"GRANT ALL PRIVILEGES ON *.* TO 'root'@'localhost' WITH GRANT OPTION"}, dbaPrivileges); len(missing) != 0 {
+		t.Errorf("Test missingPrivileges ALL PRIVILEGES failed: actual %v, expected none", missing)
+	}
+}
+
+func TestPrivilegeClause(t *testing.T) {
+	account := Account{User: "app"}
+	if _, err := privilegeClause(Account{}, []string{"SELECT"}, "*.*"); err != errAccountInvalid {
+		t.Error("Test privilegeClause empty user error: should return errAccountInvalid")
+	}
+	if _, err := privilegeClause(Account{User: `app\`}, []string{"SELECT"}, "*.*"); err != errAccountBackslash {
+		t.Error("Test privilegeClause backslash error: should return errAccountBackslash")
+	}
+	if _, err := privilegeClause(account, []string{"SELECT; DROP"}, "*.*"); err != errPrivilegeInvalid {
+		t.Error("Test privilegeClause invalid privilege error: should return errPrivilegeInvalid")
+	}
+	if _, err := privilegeClause(account, []string{"SELECT"}, "data_test"); err != errGrantLevel {
+		t.Error("Test privilegeClause invalid level error: should return errGrantLevel")
+	}
+	if clause, err := privilegeClause(account, []string{"select", "replication client"}, "`data_test`.*"); err != nil {
+		t.Errorf("Test privilegeClause error: %s", err.Error())
+	} else if clause != "SELECT, REPLICATION CLIENT ON `data_test`.*" {
+		t.Errorf("Test privilegeClause failed: actual %s", clause)
+	}
+}
+
+func TestUserManagement(t *testing.T) {
+	account := Account{User: "msops_test", Host: "%"}
+	if CreateUser(unregisteredEndpoint, account, "test") != errNotRegistered {
+		t.Error("Test CreateUser unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if err := CreateUser(testEndpoint1, account, "test"); err != nil {
+		t.Fatalf("Test CreateUser error: %s", err.Error())
+	}
+	defer DropUser(testEndpoint1, account)
+	if err := Grant(testEndpoint1, account, []string{"SELECT"}, "data_test.*"); err != nil {
+		t.Errorf("Test Grant error: %s", err.Error())
+	}
+	if grants, err := GetGrants(testEndpoint1, account); err != nil {
+		t.Errorf("Test GetGrants error: %s", err.Error())
+	} else if len(grants) != 2 {
+		t.Errorf("Test GetGrants failed: actual %v, expected 2 grants", grants)
+	}
+	if err := Revoke(testEndpoint1, account, []string{"SELECT"}, "data_test.*"); err != nil {
+		t.Errorf("Test Revoke error: %s", err.Error())
+	}
+	if err := AlterUserPassword(testEndpoint1, account, "test2"); err != nil {
+		t.Errorf("Test AlterUserPassword error: %s", err.Error())
+	}
+	if err := DropUser(testEndpoint1, account); err != nil {
+		t.Errorf("Test DropUser error: %s", err.Error())
+	}
+	if _, err := GetGrants(testEndpoint1, account); !isNoSuchGrantError(err) {
+		t.Error("Test GetGrants dropped account error: should return error 1141")
+	}
+}
+
+func TestVerifyPrivileges(t *testing.T) {
+	if _, err := VerifyPrivileges(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test VerifyPrivileges unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if report, err := VerifyPrivileges(testEndpoint1); err != nil {
+		t.Errorf("Test VerifyPrivileges error: %s", err.Error())
+	} else if !report.OK() {
+		t.Errorf("Test VerifyPrivileges failed: %+v", report)
+	}
+}
+
+func TestEnsureReplicationUser(t *testing.T) {
+	if EnsureReplicationUser(unregisteredEndpoint) != errNotRegistered {
+		t.Error("Test EnsureReplicationUser unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if err := EnsureReplicationUser(testEndpoint1); err != nil {
+		t.Errorf("Test EnsureReplicationUser error: %s", err.Error())
+	}
+	if report, err := VerifyPrivileges(testEndpoint1); err != nil {
+		t.Errorf("Test VerifyPrivileges error: %s", err.Error())
+	} else if report.ReplUserError != nil || len(report.ReplUserMissing) != 0 {
+		t.Errorf("Test EnsureReplicationUser failed: %+v", report)
+	}
+}
+
+func TestUserWithPlaceholder(t *testing.T) {
+	account := Account{User: "msops?test", Host: "%"}
+	if err := CreateUser(testEndpoint1, account, `pass?'\word`); err != nil {
+		t.Fatalf("Test CreateUser with placeholder error: %s", err.Error())
+	}
+	defer DropUser(testEndpoint1, account)
+	if _, err := GetGrants(testEndpoint1, account); err != nil {
+		t.Errorf("Test GetGrants with placeholder error: %s", err.Error())
+	}
+}
+
+func TestCheckSlavesWithoutAccount(t *testing.T) {
+	if err := ChangeMasterTo(testEndpoint2, testEndpoint1, false); err != nil {
+		t.Fatalf("Test checkSlavesWithoutAccount ChangeMasterTo error: %s", err.Error())
+	}
+	defer ResetSlave(testEndpoint2, true)
+	account := Account{User: "msops_exists", Host: "%"}
+	if err := checkSlavesWithoutAccount(testEndpoint1, account); err != nil {
+		t.Errorf("Test checkSlavesWithoutAccount error: %s", err.Error())
+	}
+	if err := CreateUser(testEndpoint2, account, "test"); err != nil {
+		t.Fatalf("Test checkSlavesWithoutAccount CreateUser error: %s", err.Error())
+	}
+	defer DropUser(testEndpoint2, account)
+	if err := checkSlavesWithoutAccount(testEndpoint1, account); err == nil {
+		t.Error("Test checkSlavesWithoutAccount with existing account error: should return error")
+	}
+}
@@ -0,0 +1,147 @@
+package msops
+
+// ConnectionStatus represents one row data of performance_schema.replication_connection_status,
+// i.e. the status of the receiver (I/O) thread of one channel.
+// Based on 8.0 MySQL Community Server, the fields not existing in 5.7 are left empty.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/8.0/en/performance-schema-replication-connection-status-table.html
+type ConnectionStatus struct {
+	ChannelName             string
+	GroupName               string
+	SourceUUID              string
+	ThreadID                int
+	ServiceState            string
+	CountReceivedHeartbeats int
+	LastHeartbeatTimestamp  string
+	ReceivedTransactionSet  string
+	LastErrorNumber         int
+	LastErrorMessage        string
+	LastErrorTimestamp      string
+	LastQueuedTransaction   string
+	QueueingTransaction     string
+}
+
+// ApplierCoordinatorStatus represents one row data of performance_schema.replication_applier_status_by_coordinator,
+// which is only filled for multi-threaded replicas.
+// Based on 8.0 MySQL Community Server, the fields not existing in 5.7 are left empty.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/8.0/en/performance-schema-replication-applier-status-by-coordinator-table.html
+type ApplierCoordinatorStatus struct {
+	ChannelName              string
+	ThreadID                 int
+	ServiceState             string
+	LastErrorNumber          int
+	LastErrorMessage         string
+	LastErrorTimestamp       string
+	LastProcessedTransaction string
+	ProcessingTransaction    string
+}
+
+// ApplierWorkerStatus represents one row data of performance_schema.replication_applier_status_by_worker.
+// Based on 8.0 MySQL Community Server, the fields not existing in 5.7 are left empty.
+//
+// LastAppliedTransaction is read from 'LAST_SEEN_TRANSACTION' in 5.7.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/8.0/en/performance-schema-replication-applier-status-by-worker-table.html
+type ApplierWorkerStatus struct {
+	ChannelName                                string
+	WorkerID                                   int
+	ThreadID                                   int
+	ServiceState                               string
+	LastErrorNumber                            int
+	LastErrorMessage                           string
+	LastErrorTimestamp                         string
+	LastAppliedTransaction                     string
+	LastAppliedOriginalCommitTimestamp         string
+	LastAppliedEndApplyTimestamp               string
+	ApplyingTransaction                        string
+	ApplyingTransactionStartApplyTimestamp     string
+	ApplyingTransactionOriginalCommitTimestamp string
+}
+
+// ApplierStatus represents the status of the receiver and applier threads of all the channels of a replica.
+type ApplierStatus struct {
+	Connections  []ConnectionStatus
+	Coordinators []ApplierCoordinatorStatus
+	Workers      []ApplierWorkerStatus
+}
+
+// GetApplierStatus reads the replication tables of performance_schema, which are available since MySQL 5.7.
+func GetApplierStatus(endpoint string) (ApplierStatus, error) {
+	var status ApplierStatus
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.replication_connection_status"); err != nil {
+		return status, err
+	}
+	for _, row := range dataSet {
+		status.Connections = append(status.Connections, ConnectionStatus{
+			ChannelName:             row["CHANNEL_NAME"],
+			GroupName:               row["GROUP_NAME"],
+			SourceUUID:              row["SOURCE_UUID"],
+			ThreadID:                getInt(row["THREAD_ID"]),
+			ServiceState:            row["SERVICE_STATE"],
+			CountReceivedHeartbeats: getInt(row["COUNT_RECEIVED_HEARTBEATS"]),
+			LastHeartbeatTimestamp:  row["LAST_HEARTBEAT_TIMESTAMP"],
+			ReceivedTransactionSet:  row["RECEIVED_TRANSACTION_SET"],
+			LastErrorNumber:         getInt(row["LAST_ERROR_NUMBER"]),
+			LastErrorMessage:        row["LAST_ERROR_MESSAGE"],
+			LastErrorTimestamp:      row["LAST_ERROR_TIMESTAMP"],
+			LastQueuedTransaction:   row["LAST_QUEUED_TRANSACTION"],
+			QueueingTransaction:     row["QUEUEING_TRANSACTION"],
+		})
+	}
+
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.replication_applier_status_by_coordinator"); err != nil {
+		return status, err
+	}
+	for _, row := range dataSet {
+		status.Coordinators = append(status.Coordinators, ApplierCoordinatorStatus{
+			ChannelName:              row["CHANNEL_NAME"],
+			ThreadID:                 getInt(row["THREAD_ID"]),
+			ServiceState:             row["SERVICE_STATE"],
+			LastErrorNumber:          getInt(row["LAST_ERROR_NUMBER"]),
+			LastErrorMessage:         row["LAST_ERROR_MESSAGE"],
+			LastErrorTimestamp:       row["LAST_ERROR_TIMESTAMP"],
+			LastProcessedTransaction: row["LAST_PROCESSED_TRANSACTION"],
+			ProcessingTransaction:    row["PROCESSING_TRANSACTION"],
+		})
+	}
+
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.replication_applier_status_by_worker"); err != nil {
+		return status, err
+	}
+	for _, row := range dataSet {
+		worker := ApplierWorkerStatus{
+			ChannelName:                            row["CHANNEL_NAME"],
+			WorkerID:                               getInt(row["WORKER_ID"]),
+			ThreadID:                               getInt(row["THREAD_ID"]),
+			ServiceState:                           row["SERVICE_STATE"],
+			LastErrorNumber:                        getInt(row["LAST_ERROR_NUMBER"]),
+			LastErrorMessage:                       row["LAST_ERROR_MESSAGE"],
+			LastErrorTimestamp:                     row["LAST_ERROR_TIMESTAMP"],
+			LastAppliedTransaction:                 row["LAST_APPLIED_TRANSACTION"],
+			LastAppliedOriginalCommitTimestamp:     row["LAST_APPLIED_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP"],
+			LastAppliedEndApplyTimestamp:           row["LAST_APPLIED_TRANSACTION_END_APPLY_TIMESTAMP"],
+			ApplyingTransaction:                    row["APPLYING_TRANSACTION"],
+			ApplyingTransactionStartApplyTimestamp: row["APPLYING_TRANSACTION_START_APPLY_TIMESTAMP"],
+			ApplyingTransactionOriginalCommitTimestamp: row["APPLYING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP"],
+		}
+		if seen, exists := row["LAST_SEEN_TRANSACTION"]; exists {
+			worker.LastAppliedTransaction = seen
+		}
+		status.Workers = append(status.Workers, worker)
+	}
+	return status, nil
+}
+
+// FailedWorkers returns the workers whose last error is not empty.
+func (status ApplierStatus) FailedWorkers() []ApplierWorkerStatus {
+	var workers []ApplierWorkerStatus
+	for _, worker := range status.Workers {
+		if worker.LastErrorNumber != 0 {
+			workers = append(workers, worker)
+		}
+	}
+	return workers
+}
@@ -0,0 +1,20 @@
+package msops
+
+import "testing"
+
+func TestGetApplierStatus(t *testing.T) {
+	if _, err := GetApplierStatus(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test GetApplierStatus unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := GetApplierStatus(badEndpoint); err == nil {
+		t.Error("Get badEndpoint applier status should cause error")
+	}
+	if v, err := getVersion(testEndpoint1); err != nil || !v.atLeast(5, 7, 0) {
+		t.Skip("replication tables of performance_schema are not available before MySQL 5.7")
+	}
+	if status, err := GetApplierStatus(testEndpoint1); err != nil {
+		t.Errorf("Test GetApplierStatus error: %s", err.Error())
+	} else if len(status.FailedWorkers()) != 0 {
+		t.Errorf("Test GetApplierStatus failed: actual failed workers %+v, expected none", status.FailedWorkers())
+	}
+}
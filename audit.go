@@ -0,0 +1,136 @@
+package msops
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditTags represents the caller-supplied information attached to audit entries.
+type AuditTags struct {
+	Operator string            `json:"operator,omitempty"`
+	Reason   string            `json:"reason,omitempty"`
+	Extra    map[string]string `json:"extra,omitempty"`
+}
+
+// AuditEntry records one mutating statement executed by msops.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+
+	// Statement is the executed statement with secrets such as passwords redacted.
+	Statement string        `json:"statement"`
+	Tags      AuditTags     `json:"tags"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// AuditHook is invoked for every mutating statement executed by msops,
+// including KILL, SET GLOBAL, CHANGE MASTER, RESET SLAVE, etc.
+//
+// Errors returned by the hook are ignored by the operations.
+type AuditHook interface {
+	Audit(entry AuditEntry) error
+}
+
+var (
+	// auditMutex guards auditHook and auditTags.
+	auditMutex sync.RWMutex
+	auditHook  AuditHook
+	auditTags  AuditTags
+)
+
+// SetAuditHook sets the hook invoked for every mutating statement. A nil hook disables auditing.
+func SetAuditHook(hook AuditHook) {
+	auditMutex.Lock()
+	auditHook = hook
+	auditMutex.Unlock()
+}
+
+// SetAuditTags sets the tags attached to the audit entries of the following statements.
+//
+// The tags are process-wide: they're attached to the statements of all the goroutines until they're set again.
+// Operations run concurrently by different operators should use ExecutePlanWithTags instead.
+func SetAuditTags(tags AuditTags) {
+	auditMutex.Lock()
+	auditTags = tags
+	auditMutex.Unlock()
+}
+
+// audit sends the entry of stmt to the audit hook if it's set. tags override the process-wide ones if not nil.
+func audit(stmt Statement, tags *AuditTags, start time.Time, err error) {
+	auditMutex.RLock()
+	hook := auditHook
+	if tags == nil {
+		tags = &auditTags
+	}
+	entry := AuditEntry{
+		Time:      start,
+		Endpoint:  stmt.Endpoint,
+		Statement: stmt.Query,
+		Tags:      *tags,
+		Duration:  time.Since(start),
+	}
+	auditMutex.RUnlock()
+	if hook == nil {
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	hook.Audit(entry)
+}
+
+// MemoryAuditSink keeps the audit entries in memory. It's safe for concurrent use.
+type MemoryAuditSink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// Audit appends the entry.
+func (s *MemoryAuditSink) Audit(entry AuditEntry) error {
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+	return nil
+}
+
+// Entries returns a copy of the recorded entries.
+func (s *MemoryAuditSink) Entries() []AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEntry(nil), s.entries...)
+}
+
+// JSONLinesAuditSink appends the audit entries to a file in JSON lines format. It's safe for concurrent use.
+type JSONLinesAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJSONLinesAuditSink opens or creates the file of path for appending audit entries.
+func NewJSONLinesAuditSink(path string) (*JSONLinesAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesAuditSink{file: file}, nil
+}
+
+// Audit writes the entry as one line of JSON.
+func (s *JSONLinesAuditSink) Audit(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the underlying file.
+func (s *JSONLinesAuditSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,91 @@
+package msops
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAuditHook(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	SetAuditHook(sink)
+	SetAuditTags(AuditTags{Operator: "tester", Reason: "TestAuditHook"})
+	defer SetAuditHook(nil)
+	defer SetAuditTags(AuditTags{})
+
+	if err := StopSlave(testEndpoint3); err != nil {
+		t.Errorf("Test AuditHook StopSlave error: %s", err.Error())
+	}
+	StopSlave(badEndpoint)
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Test AuditHook failed: actual %d entries, expected 2", len(entries))
+	}
+	if entries[0].Endpoint != testEndpoint3 || entries[0].Statement != "STOP SLAVE" ||
+		entries[0].Tags.Operator != "tester" || entries[0].Error != "" {
+		t.Errorf("Test AuditHook failed: unexpected entry %+v", entries[0])
+	}
+	if entries[1].Error == "" {
+		t.Error("Test AuditHook failed: error of badEndpoint is not recorded")
+	}
+}
+
+func TestExecutePlanWithTags(t *testing.T) {
+	sink := &MemoryAuditSink{}
+	SetAuditHook(sink)
+	SetAuditTags(AuditTags{Operator: "global"})
+	defer SetAuditHook(nil)
+	defer SetAuditTags(AuditTags{})
+
+	plan, err := StartDryRunFor(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test StartDryRunFor error: %s", err.Error())
+	}
+	StopSlave(testEndpoint3)
+	plan.Stop()
+	if err = ExecutePlanWithTags(plan, AuditTags{Operator: "tester"}); err != nil {
+		t.Errorf("Test ExecutePlanWithTags error: %s", err.Error())
+	}
+	if entries := sink.Entries(); len(entries) != 1 || entries[0].Tags.Operator != "tester" {
+		t.Errorf("Test ExecutePlanWithTags failed: unexpected entries %+v", entries)
+	}
+}
+
+func TestJSONLinesAuditSink(t *testing.T) {
+	file, err := ioutil.TempFile("", "msops-audit")
+	if err != nil {
+		t.Fatalf("Test JSONLinesAuditSink create temp file error: %s", err.Error())
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	sink, err := NewJSONLinesAuditSink(file.Name())
+	if err != nil {
+		t.Fatalf("Test NewJSONLinesAuditSink error: %s", err.Error())
+	}
+	for _, endpoint := range []string{testEndpoint1, testEndpoint2} {
+		if err = sink.Audit(AuditEntry{Endpoint: endpoint, Statement: "STOP SLAVE"}); err != nil {
+			t.Errorf("Test JSONLinesAuditSink Audit error: %s", err.Error())
+		}
+	}
+	sink.Close()
+
+	if file, err = os.Open(file.Name()); err != nil {
+		t.Fatalf("Test JSONLinesAuditSink open file error: %s", err.Error())
+	}
+	defer file.Close()
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Errorf("Test JSONLinesAuditSink unmarshal error: %s", err.Error())
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("Test JSONLinesAuditSink failed: actual %d lines, expected 2", lines)
+	}
+}
@@ -0,0 +1,407 @@
+package msops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// BinaryLog represents one row data of "SHOW BINARY LOGS".
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.6/en/show-binary-logs.html
+type BinaryLog struct {
+	LogName   string
+	FileSize  int
+	Encrypted string
+}
+
+// PurgeOptions limits the binary logs purged by PurgeBinaryLogs.
+type PurgeOptions struct {
+	// To purges the binary logs before it at most. Empty means as many as safe.
+	To string
+
+	// KeepFiles keeps at least the newest KeepFiles binary logs.
+	KeepFiles int
+
+	// KeepFor keeps the binary logs having events newer than KeepFor, i.e. a binary log is purged
+	// only if the next one was created more than KeepFor ago.
+	KeepFor time.Duration
+
+	// Replicas are the endpoints whose replication from the master are protected, which must be replicating
+	// from the master. All the registered instances are checked if it's empty.
+	Replicas []string
+}
+
+var (
+	errBinlogNotFound = errors.New("the binary log is not found")
+	errNoBinaryLogs   = errors.New("binary logging is not enabled")
+	errNoCreateTime   = errors.New("the creation time of the binary log is not found")
+)
+
+// BinlogEvent represents one row data of "SHOW BINLOG EVENTS".
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.6/en/show-binlog-events.html
+//
+// The fields after Info are parsed from 'Info' according to 'Event_type', and are left empty for other types.
+type BinlogEvent struct {
+	LogName   string
+	Pos       int
+	EventType string
+	ServerID  int
+	EndLogPos int
+	Info      string
+
+	// GTID is set for "Gtid" events.
+	GTID string
+
+	// Database and Query are set for "Query" events.
+	Database string
+	Query    string
+
+	// TableID and Table are set for "Table_map" events, Table is in the form "db.table".
+	TableID int
+	Table   string
+
+	// Xid is set for "Xid" events.
+	Xid int
+
+	// NextLogName and NextLogPos are set for "Rotate" events.
+	NextLogName string
+	NextLogPos  int
+}
+
+var (
+	gtidInfoExp     = regexp.MustCompile(`GTID_NEXT\s*=\s*'([^']+)'`)
+	queryInfoExp    = regexp.MustCompile("(?s)^use `([^`]*)`; (.*)$")
+	tableMapInfoExp = regexp.MustCompile(`^table_id: (\d+) \(([^)]*)\)`)
+	xidInfoExp      = regexp.MustCompile(`xid=(\d+)`)
+	rotateInfoExp   = regexp.MustCompile(`^(.+);pos=(\d+)$`)
+)
+
+// ShowBinlogEvents executes "SHOW BINLOG EVENTS IN file FROM pos LIMIT limit" and returns the resultset.
+//
+// The first binary log is used if file is empty, and all the events are returned if limit is not positive.
+func ShowBinlogEvents(endpoint, file string, pos, limit int) ([]BinlogEvent, error) {
+	return showEvents(endpoint, "SHOW BINLOG EVENTS", file, pos, limit)
+}
+
+// ShowRelayLogEvents executes "SHOW RELAYLOG EVENTS IN file FROM pos LIMIT limit" and returns the resultset.
+//
+// The first relay log is used if file is empty, and all the events are returned if limit is not positive.
+func ShowRelayLogEvents(endpoint, file string, pos, limit int) ([]BinlogEvent, error) {
+	return showEvents(endpoint, "SHOW RELAYLOG EVENTS", file, pos, limit)
+}
+
+// showEvents executes the statement of "SHOW BINLOG EVENTS" or "SHOW RELAYLOG EVENTS" and parses the resultset.
+func showEvents(endpoint, query, file string, pos, limit int) ([]BinlogEvent, error) {
+	var args []interface{}
+	if file != "" {
+		query += " IN ?"
+		args = append(args, file)
+	}
+	if pos > 0 {
+		query += " FROM ?"
+		args = append(args, pos)
+	}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, query, args...); err != nil {
+		return nil, err
+	}
+	events := make([]BinlogEvent, 0, len(dataSet))
+	for _, row := range dataSet {
+		event := BinlogEvent{
+			LogName:   row["Log_name"],
+			Pos:       getInt(row["Pos"]),
+			EventType: row["Event_type"],
+			ServerID:  getInt(row["Server_id"]),
+			EndLogPos: getInt(row["End_log_pos"]),
+			Info:      row["Info"],
+		}
+		switch event.EventType {
+		case "Gtid":
+			if matches := gtidInfoExp.FindStringSubmatch(event.Info); len(matches) == 2 {
+				event.GTID = matches[1]
+			}
+		case "Query":
+			if matches := queryInfoExp.FindStringSubmatch(event.Info); len(matches) == 3 {
+				event.Database, event.Query = matches[1], matches[2]
+			} else {
+				event.Query = event.Info
+			}
+		case "Table_map":
+			if matches := tableMapInfoExp.FindStringSubmatch(event.Info); len(matches) == 3 {
+				event.TableID, event.Table = getInt(matches[1]), matches[2]
+			}
+		case "Xid":
+			if matches := xidInfoExp.FindStringSubmatch(event.Info); len(matches) == 2 {
+				event.Xid = getInt(matches[1])
+			}
+		case "Rotate":
+			if matches := rotateInfoExp.FindStringSubmatch(event.Info); len(matches) == 3 {
+				event.NextLogName, event.NextLogPos = matches[1], getInt(matches[2])
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// ListBinaryLogs executes "SHOW BINARY LOGS" and returns the resultset.
+func ListBinaryLogs(endpoint string) ([]BinaryLog, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SHOW BINARY LOGS"); err != nil {
+		return nil, err
+	}
+	logs := make([]BinaryLog, 0, len(dataSet))
+	for _, row := range dataSet {
+		logs = append(logs, BinaryLog{
+			LogName:   row["Log_name"],
+			FileSize:  getInt(row["File_size"]),
+			Encrypted: row["Encrypted"],
+		})
+	}
+	return logs, nil
+}
+
+// FlushBinaryLogs executes "FLUSH BINARY LOGS" at the endpoint.
+func FlushBinaryLogs(endpoint string) error {
+	return execute(endpoint, "FLUSH BINARY LOGS")
+}
+
+// PurgeBinaryLogs executes "PURGE BINARY LOGS TO file" at the master, where file is the oldest binary log
+// still needed by the replicas, or limited by opts. It returns the file purged to,
+// or an empty string if no binary log can be purged.
+//
+// A registered instance is considered as a replica of the master if its 'Master_UUID' is the server_uuid
+// of the master, or if its 'Master_Host' resolves to the same address as the master's host with the same port
+// when it has never connected to the master. The binary logs of both 'Relay_Master_Log_File' and
+// 'Master_Log_File' are kept. For replicas using GTID auto position, it's also checked that the replica has
+// executed all the GTIDs of the purged binary logs.
+//
+// If any of the replicas can't be checked, or a replica connected to the master (listed by "SHOW SLAVE HOSTS")
+// is not one of the checked replicas, an error is returned without purging.
+func PurgeBinaryLogs(masterEndpoint string, opts PurgeOptions) (string, error) {
+	var logs []BinaryLog
+	var err error
+	if logs, err = ListBinaryLogs(masterEndpoint); err != nil {
+		return "", err
+	}
+	if len(logs) == 0 {
+		return "", errNoBinaryLogs
+	}
+	// Index of the first binary log to be kept. The active one is always kept.
+	keep := len(logs) - 1
+	if opts.KeepFiles > 0 && len(logs)-opts.KeepFiles < keep {
+		keep = len(logs) - opts.KeepFiles
+	}
+	if opts.To != "" {
+		idx := binaryLogIndex(logs, opts.To)
+		if idx < 0 {
+			return "", errBinlogNotFound
+		}
+		if idx < keep {
+			keep = idx
+		}
+	}
+
+	masterVars, err := GetGlobalVariables(masterEndpoint, "server_uuid")
+	if err != nil {
+		return "", err
+	}
+	replicas := opts.Replicas
+	if len(replicas) == 0 {
+		for _, endpoint := range registeredEndpoints() {
+			if endpoint != masterEndpoint {
+				replicas = append(replicas, endpoint)
+			}
+		}
+	}
+	var autoPositionGtids []string
+	checked := make(map[string]bool)
+	for _, replica := range replicas {
+		slaveStatus, err := GetSlaveStatus(replica)
+		if err != nil {
+			return "", fmt.Errorf("can't check replica %s: %s", replica, err.Error())
+		}
+		matched, err := isReplicaOf(slaveStatus, masterEndpoint, masterVars["server_uuid"])
+		if err != nil {
+			return "", fmt.Errorf("can't check replica %s: %s", replica, err.Error())
+		}
+		if !matched {
+			if len(opts.Replicas) > 0 {
+				return "", fmt.Errorf("%s is not replicating from %s", replica, masterEndpoint)
+			}
+			continue
+		}
+		replicaVars, err := GetGlobalVariables(replica, "server_uuid")
+		if err != nil {
+			return "", fmt.Errorf("can't check replica %s: %s", replica, err.Error())
+		}
+		checked[replicaVars["server_uuid"]] = true
+		for _, file := range []string{slaveStatus.RelayMasterLogFile, slaveStatus.MasterLogFile} {
+			if idx := binaryLogIndex(logs, file); idx >= 0 && idx < keep {
+				keep = idx
+			}
+		}
+		if slaveStatus.AutoPosition {
+			autoPositionGtids = append(autoPositionGtids, slaveStatus.ExecutedGtidSet)
+		}
+	}
+	hosts, err := readDataSet(masterEndpoint, "SHOW SLAVE HOSTS")
+	if err != nil {
+		return "", err
+	}
+	for _, host := range hosts {
+		uuid := host["Slave_UUID"]
+		if uuid == "" {
+			uuid = host["Replica_UUID"]
+		}
+		if !checked[uuid] {
+			return "", fmt.Errorf("replica %s (server_id %s) is connected but not checked", uuid, host["Server_id"])
+		}
+	}
+	if keep > 0 && opts.KeepFor > 0 {
+		deadline := time.Now().Add(-opts.KeepFor)
+		for i := 1; i <= keep; i++ {
+			created, err := binaryLogCreateTime(masterEndpoint, logs[i].LogName)
+			if err != nil {
+				return "", err
+			}
+			if created.After(deadline) {
+				keep = i - 1
+				break
+			}
+		}
+	}
+	if keep <= 0 {
+		return "", nil
+	}
+
+	if len(autoPositionGtids) > 0 {
+		purgedGtids, err := previousGtids(masterEndpoint, logs[keep].LogName)
+		if err != nil {
+			return "", err
+		}
+		for _, executed := range autoPositionGtids {
+			if subset, err := isGtidSubset(masterEndpoint, purgedGtids, executed); err != nil {
+				return "", err
+			} else if !subset {
+				return "", fmt.Errorf("GTIDs of the binary logs before %s are not executed by all the replicas", logs[keep].LogName)
+			}
+		}
+	}
+	if err = execute(masterEndpoint, "PURGE BINARY LOGS TO ?", logs[keep].LogName); err != nil {
+		return "", err
+	}
+	return logs[keep].LogName, nil
+}
+
+// isReplicaOf reports whether the slave status is replicating from the master of masterEndpoint and masterUUID.
+// The hosts are resolved to compare if the slave has never connected to the master.
+func isReplicaOf(slaveStatus SlaveStatus, masterEndpoint, masterUUID string) (bool, error) {
+	if reflect.DeepEqual(emptySlaveStatus, slaveStatus) {
+		return false, nil
+	}
+	if slaveStatus.MasterUUID != "" {
+		return slaveStatus.MasterUUID == masterUUID, nil
+	}
+	host, port, err := net.SplitHostPort(masterEndpoint)
+	if err != nil {
+		return false, err
+	}
+	if port != strconv.Itoa(slaveStatus.MasterPort) {
+		return false, nil
+	}
+	return sameHost(host, slaveStatus.MasterHost)
+}
+
+// sameHost reports whether the hosts resolve to any same address.
+func sameHost(a, b string) (bool, error) {
+	if a == b {
+		return true, nil
+	}
+	addrsA, err := net.LookupHost(a)
+	if err != nil {
+		return false, err
+	}
+	addrsB, err := net.LookupHost(b)
+	if err != nil {
+		return false, err
+	}
+	for _, addrA := range addrsA {
+		for _, addrB := range addrsB {
+			if net.ParseIP(addrA).Equal(net.ParseIP(addrB)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// binaryLogCreateTime reads the timestamp of the Format_description event of the binary log,
+// which is the time the binary log is created.
+func binaryLogCreateTime(endpoint, file string) (time.Time, error) {
+	streamer, err := StartBinlogStream(endpoint, BinlogStreamOptions{File: file, NonBlock: true, HeaderOnly: true})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer streamer.Close()
+	for {
+		event, err := streamer.Next()
+		if err == io.EOF {
+			return time.Time{}, errNoCreateTime
+		} else if err != nil {
+			return time.Time{}, err
+		}
+		if event.Header.EventType == FormatDescriptionEventType {
+			return time.Unix(int64(event.Header.Timestamp), 0), nil
+		}
+	}
+}
+
+// binaryLogIndex returns the index of the binary log named file in logs, or -1 if it's not found.
+func binaryLogIndex(logs []BinaryLog, file string) int {
+	for i, log := range logs {
+		if log.LogName == file {
+			return i
+		}
+	}
+	return -1
+}
+
+// previousGtids returns the GTID set of the Previous_gtids event of the binary log,
+// i.e. the GTIDs of all the binary logs before it.
+func previousGtids(endpoint, file string) (string, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SHOW BINLOG EVENTS IN ? LIMIT 3", file); err != nil {
+		return "", err
+	}
+	for _, row := range dataSet {
+		if row["Event_type"] == "Previous_gtids" {
+			return row["Info"], nil
+		}
+	}
+	return "", nil
+}
+
+// isGtidSubset executes "SELECT GTID_SUBSET(subset, set)" at the endpoint.
+func isGtidSubset(endpoint, subset, set string) (bool, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SELECT GTID_SUBSET(?, ?) AS is_subset", subset, set); err != nil {
+		return false, err
+	}
+	return len(dataSet) == 1 && dataSet[0]["is_subset"] == "1", nil
+}
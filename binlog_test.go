@@ -0,0 +1,105 @@
+package msops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListAndFlushBinaryLogs(t *testing.T) {
+	logs, err := ListBinaryLogs(testEndpoint1)
+	if err != nil {
+		t.Fatalf("Test ListBinaryLogs error: %s", err.Error())
+	}
+	if err = FlushBinaryLogs(testEndpoint1); err != nil {
+		t.Errorf("Test FlushBinaryLogs error: %s", err.Error())
+	}
+	if flushed, err := ListBinaryLogs(testEndpoint1); err != nil {
+		t.Errorf("Test ListBinaryLogs error: %s", err.Error())
+	} else if len(flushed) != len(logs)+1 {
+		t.Errorf("Test FlushBinaryLogs failed: actual %d binary logs, expected %d", len(flushed), len(logs)+1)
+	}
+	if _, err = ListBinaryLogs(badEndpoint); err == nil {
+		t.Error("List badEndpoint binary logs should cause error")
+	}
+	if FlushBinaryLogs(unregisteredEndpoint) != errNotRegistered {
+		t.Error("Test FlushBinaryLogs unregisteredEndpoint error: should return errNotRegistered")
+	}
+}
+
+func TestPurgeBinaryLogs(t *testing.T) {
+	if _, err := PurgeBinaryLogs(testEndpoint1, PurgeOptions{To: "not-exists.000001"}); err != errBinlogNotFound {
+		t.Error("Test PurgeBinaryLogs with unknown file error: should return errBinlogNotFound")
+	}
+
+	FlushBinaryLogs(testEndpoint1)
+	if err := ChangeMasterTo(testEndpoint2, testEndpoint1, false); err != nil {
+		t.Fatalf("Test PurgeBinaryLogs ChangeMasterTo error: %s", err.Error())
+	}
+	defer ResetSlave(testEndpoint2, true)
+	masterSt, err := GetMasterStatus(testEndpoint1)
+	if err != nil {
+		t.Fatalf("Test PurgeBinaryLogs GetMasterStatus error: %s", err.Error())
+	}
+	FlushBinaryLogs(testEndpoint1)
+	FlushBinaryLogs(testEndpoint1)
+
+	if purgedTo, err := PurgeBinaryLogs(testEndpoint1, PurgeOptions{Replicas: []string{testEndpoint2}}); err != nil {
+		t.Errorf("Test PurgeBinaryLogs error: %s", err.Error())
+	} else if purgedTo != masterSt.File {
+		t.Errorf("Test PurgeBinaryLogs failed: actual purged to %s, expected %s", purgedTo, masterSt.File)
+	} else if logs, err := ListBinaryLogs(testEndpoint1); err != nil {
+		t.Errorf("Test PurgeBinaryLogs ListBinaryLogs error: %s", err.Error())
+	} else if logs[0].LogName != masterSt.File {
+		t.Errorf("Test PurgeBinaryLogs failed: actual oldest binary log %s, expected %s", logs[0].LogName, masterSt.File)
+	}
+
+	FlushBinaryLogs(testEndpoint1)
+	if purgedTo, err := PurgeBinaryLogs(testEndpoint1, PurgeOptions{KeepFor: time.Hour}); err != nil {
+		t.Errorf("Test PurgeBinaryLogs KeepFor error: %s", err.Error())
+	} else if purgedTo != "" {
+		t.Errorf("Test PurgeBinaryLogs KeepFor failed: the binary logs of the last hour are purged to %s", purgedTo)
+	}
+	if _, err := PurgeBinaryLogs(testEndpoint1, PurgeOptions{Replicas: []string{testEndpoint3}}); err == nil {
+		t.Error("Test PurgeBinaryLogs with replica of other master error: should return error")
+	}
+	if _, err := PurgeBinaryLogs(testEndpoint1, PurgeOptions{Replicas: []string{badEndpoint}}); err == nil {
+		t.Error("Test PurgeBinaryLogs with badEndpoint replica error: should return error")
+	}
+}
+
+func TestShowBinlogEvents(t *testing.T) {
+	if events, err := ShowBinlogEvents(testEndpoint1, "", 0, 2); err != nil {
+		t.Errorf("Test ShowBinlogEvents error: %s", err.Error())
+	} else if len(events) != 2 {
+		t.Errorf("Test ShowBinlogEvents failed: actual %d events, expected 2", len(events))
+	} else if events[0].EventType != "Format_desc" || events[0].Pos != 4 {
+		t.Errorf("Test ShowBinlogEvents failed: actual first event %s at %d, expected Format_desc at 4", events[0].EventType, events[0].Pos)
+	}
+	if _, err := ShowBinlogEvents(testEndpoint1, "not-exists.000001", 0, 0); err == nil {
+		t.Error("Test ShowBinlogEvents with unknown file error: should return error")
+	}
+	if _, err := ShowBinlogEvents(badEndpoint, "", 0, 0); err == nil {
+		t.Error("Show badEndpoint binlog events should cause error")
+	}
+}
+
+func TestIsReplicaOf(t *testing.T) {
+	slaveStatus := SlaveStatus{MasterHost: "localhost", MasterPort: 3301}
+	if matched, err := isReplicaOf(slaveStatus, testEndpoint1, "uuid-1"); err != nil {
+		t.Errorf("Test isReplicaOf error: %s", err.Error())
+	} else if !matched {
+		t.Error("Test isReplicaOf failed: localhost should match 127.0.0.1")
+	}
+	slaveStatus.MasterUUID = "uuid-2"
+	if matched, err := isReplicaOf(slaveStatus, testEndpoint1, "uuid-1"); err != nil || matched {
+		t.Error("Test isReplicaOf failed: Master_UUID should be compared if it's known")
+	}
+	slaveStatus = SlaveStatus{MasterHost: "127.0.0.1", MasterPort: 3302}
+	if matched, err := isReplicaOf(slaveStatus, testEndpoint1, "uuid-1"); err != nil || matched {
+		t.Error("Test isReplicaOf failed: different ports should not match")
+	}
+	slaveStatus = SlaveStatus{MasterHost: "not-exists.invalid", MasterPort: 3301}
+	if _, err := isReplicaOf(slaveStatus, testEndpoint1, "uuid-1"); err == nil {
+		t.Error("Test isReplicaOf with unresolvable host error: should return error")
+	}
+}
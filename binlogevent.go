@@ -0,0 +1,738 @@
+package msops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BinlogEventType represents the type code of a binlog event.
+//
+// Type specification can be found at https://dev.mysql.com/doc/internals/en/binlog-event-type.html
+type BinlogEventType byte
+
+// The binlog event types decoded by BinlogDecoder. Events of other types are returned without decoded data.
+const (
+	QueryEventType             BinlogEventType = 2
+	RotateEventType            BinlogEventType = 4
+	FormatDescriptionEventType BinlogEventType = 15
+	XidEventType               BinlogEventType = 16
+	TableMapEventType          BinlogEventType = 19
+	WriteRowsEventV1Type       BinlogEventType = 23
+	UpdateRowsEventV1Type      BinlogEventType = 24
+	DeleteRowsEventV1Type      BinlogEventType = 25
+	HeartbeatEventType         BinlogEventType = 27
+	WriteRowsEventV2Type       BinlogEventType = 30
+	UpdateRowsEventV2Type      BinlogEventType = 31
+	DeleteRowsEventV2Type      BinlogEventType = 32
+	GtidEventType              BinlogEventType = 33
+	AnonymousGtidEventType     BinlogEventType = 34
+	PreviousGtidsEventType     BinlogEventType = 35
+)
+
+// The column types of table map events.
+const (
+	mysqlTypeDecimal    = 0
+	mysqlTypeTiny       = 1
+	mysqlTypeShort      = 2
+	mysqlTypeLong       = 3
+	mysqlTypeFloat      = 4
+	mysqlTypeDouble     = 5
+	mysqlTypeNull       = 6
+	mysqlTypeTimestamp  = 7
+	mysqlTypeLongLong   = 8
+	mysqlTypeInt24      = 9
+	mysqlTypeDate       = 10
+	mysqlTypeTime       = 11
+	mysqlTypeDatetime   = 12
+	mysqlTypeYear       = 13
+	mysqlTypeVarchar    = 15
+	mysqlTypeBit        = 16
+	mysqlTypeTimestamp2 = 17
+	mysqlTypeDatetime2  = 18
+	mysqlTypeTime2      = 19
+	mysqlTypeJSON       = 245
+	mysqlTypeNewDecimal = 246
+	mysqlTypeEnum       = 247
+	mysqlTypeSet        = 248
+	mysqlTypeTinyBlob   = 249
+	mysqlTypeMediumBlob = 250
+	mysqlTypeLongBlob   = 251
+	mysqlTypeBlob       = 252
+	mysqlTypeVarString  = 253
+	mysqlTypeString     = 254
+	mysqlTypeGeometry   = 255
+)
+
+const (
+	binlogFileMagic       = "\xfebin"
+	binlogEventHeaderSize = 19
+	binlogChecksumSize    = 4
+	binlogChecksumCRC32   = 1
+)
+
+var (
+	errBinlogMagic       = errors.New("the file is not a binary log")
+	errBinlogEventShort  = errors.New("the binlog event is truncated")
+	errBinlogChecksum    = errors.New("the checksum of binlog event mismatches")
+	errBinlogTableNotMap = errors.New("the table of rows event is not mapped")
+)
+
+// BinlogEventHeader represents the common header of binlog events.
+type BinlogEventHeader struct {
+	Timestamp uint32
+	EventType BinlogEventType
+	ServerID  uint32
+	EventSize uint32
+	LogPos    uint32
+	Flags     uint16
+}
+
+// ReplicationEvent represents one decoded binlog event.
+//
+// Data is one of *FormatDescriptionEvent, *QueryEvent, *RotateEvent, *XidEvent, *GtidEvent,
+// *TableMapEvent and *RowsEvent according to Header.EventType, or nil for the other types.
+type ReplicationEvent struct {
+	Header BinlogEventHeader
+	Data   interface{}
+}
+
+// FormatDescriptionEvent describes the format of the following events in the binary log.
+type FormatDescriptionEvent struct {
+	BinlogVersion     uint16
+	ServerVersion     string
+	CreateTimestamp   uint32
+	HeaderLength      uint8
+	PostHeaderLengths []byte
+	ChecksumAlgorithm byte
+}
+
+// QueryEvent is written for statements, including "BEGIN" of row based transactions.
+type QueryEvent struct {
+	ThreadID  uint32
+	ExecTime  uint32
+	ErrorCode uint16
+	Database  string
+	Query     string
+}
+
+// RotateEvent points to the next binary log.
+type RotateEvent struct {
+	Position uint64
+	NextLog  string
+}
+
+// XidEvent is written for the commit of InnoDB transactions.
+type XidEvent struct {
+	Xid uint64
+}
+
+// GtidEvent precedes the transaction of the GTID.
+type GtidEvent struct {
+	CommitFlag bool
+	GTID       string
+}
+
+// TableMapEvent maps a table id to the definition of the table used by the following rows events.
+type TableMapEvent struct {
+	TableID     uint64
+	Schema      string
+	Table       string
+	ColumnTypes []byte
+	ColumnMeta  []uint16
+	NullBitmap  []byte
+}
+
+// RowsEvent represents the rows written, updated or deleted in one table.
+//
+// Column values are decoded into int64, float32, float64, string, []byte, uint64 (BIT), time.Time (TIMESTAMP)
+// or nil (NULL). Integers are always decoded as signed since the binary log doesn't record signedness,
+// and DECIMAL, DATE, DATETIME and TIME are formatted as strings. JSON and GEOMETRY are returned as raw bytes.
+type RowsEvent struct {
+	TableID uint64
+	Table   *TableMapEvent
+	Flags   uint16
+
+	// Rows are the written rows, the deleted rows, or the after images of the updated rows.
+	Rows [][]interface{}
+
+	// BeforeRows are the before images of the updated rows, corresponding to Rows.
+	BeforeRows [][]interface{}
+}
+
+// BinlogDecoder decodes binlog events. It keeps the format description and the table maps
+// decoded from the previous events, so the events of one stream should be decoded in order.
+type BinlogDecoder struct {
+	// HeaderOnly decodes the headers only, leaving Data of the events nil except the format description,
+	// rotate, query and xid events, which are cheap and needed to track the positions and transaction boundaries.
+	// The rows are not decoded, so that unsupported row formats don't fail.
+	HeaderOnly bool
+
+	format *FormatDescriptionEvent
+	tables map[uint64]*TableMapEvent
+}
+
+// NewBinlogDecoder creates an empty BinlogDecoder.
+func NewBinlogDecoder() *BinlogDecoder {
+	return &BinlogDecoder{tables: make(map[uint64]*TableMapEvent)}
+}
+
+// Decode decodes one binlog event including its header, and verifies its checksum if it's enabled.
+func (d *BinlogDecoder) Decode(data []byte) (*ReplicationEvent, error) {
+	if len(data) < binlogEventHeaderSize {
+		return nil, errBinlogEventShort
+	}
+	event := &ReplicationEvent{Header: BinlogEventHeader{
+		Timestamp: binary.LittleEndian.Uint32(data[0:]),
+		EventType: BinlogEventType(data[4]),
+		ServerID:  binary.LittleEndian.Uint32(data[5:]),
+		EventSize: binary.LittleEndian.Uint32(data[9:]),
+		LogPos:    binary.LittleEndian.Uint32(data[13:]),
+		Flags:     binary.LittleEndian.Uint16(data[17:]),
+	}}
+	if int(event.Header.EventSize) != len(data) {
+		return nil, errBinlogEventShort
+	}
+	if event.Header.EventType == FormatDescriptionEventType {
+		format, err := decodeFormatDescription(data)
+		if err != nil {
+			return nil, err
+		}
+		d.format = format
+		event.Data = format
+		return event, nil
+	}
+
+	switch {
+	case d.format != nil && d.format.ChecksumAlgorithm == binlogChecksumCRC32:
+		if len(data) < binlogEventHeaderSize+binlogChecksumSize {
+			return nil, errBinlogEventShort
+		}
+		if !hasChecksum(data) {
+			return nil, errBinlogChecksum
+		}
+		data = data[:len(data)-binlogChecksumSize]
+	case d.format == nil && len(data) >= binlogEventHeaderSize+binlogChecksumSize && hasChecksum(data):
+		// The fake rotate event sent before the format description by the master has checksum if enabled.
+		data = data[:len(data)-binlogChecksumSize]
+	}
+	body := data[binlogEventHeaderSize:]
+	if d.HeaderOnly {
+		switch event.Header.EventType {
+		case RotateEventType, QueryEventType, XidEventType:
+		default:
+			return event, nil
+		}
+	}
+
+	var err error
+	switch event.Header.EventType {
+	case QueryEventType:
+		event.Data, err = decodeQueryEvent(body)
+	case RotateEventType:
+		if len(body) < 8 {
+			return nil, errBinlogEventShort
+		}
+		event.Data = &RotateEvent{Position: binary.LittleEndian.Uint64(body), NextLog: string(body[8:])}
+	case XidEventType:
+		if len(body) < 8 {
+			return nil, errBinlogEventShort
+		}
+		event.Data = &XidEvent{Xid: binary.LittleEndian.Uint64(body)}
+	case GtidEventType:
+		event.Data, err = decodeGtidEvent(body)
+	case TableMapEventType:
+		var table *TableMapEvent
+		if table, err = d.decodeTableMapEvent(body); err == nil {
+			d.tables[table.TableID] = table
+			event.Data = table
+		}
+	case WriteRowsEventV1Type, UpdateRowsEventV1Type, DeleteRowsEventV1Type,
+		WriteRowsEventV2Type, UpdateRowsEventV2Type, DeleteRowsEventV2Type:
+		event.Data, err = d.decodeRowsEvent(event.Header.EventType, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// postHeaderLength returns the post header length of the event type from the format description.
+func (d *BinlogDecoder) postHeaderLength(eventType BinlogEventType, defaultLength int) int {
+	if d.format != nil && int(eventType) <= len(d.format.PostHeaderLengths) && eventType > 0 {
+		return int(d.format.PostHeaderLengths[eventType-1])
+	}
+	return defaultLength
+}
+
+// hasChecksum reports whether the last 4 bytes of data are the CRC32 checksum of the others.
+func hasChecksum(data []byte) bool {
+	n := len(data) - binlogChecksumSize
+	return crc32.ChecksumIEEE(data[:n]) == binary.LittleEndian.Uint32(data[n:])
+}
+
+func decodeFormatDescription(data []byte) (*FormatDescriptionEvent, error) {
+	body := data[binlogEventHeaderSize:]
+	if len(body) < 57 {
+		return nil, errBinlogEventShort
+	}
+	format := &FormatDescriptionEvent{
+		BinlogVersion:   binary.LittleEndian.Uint16(body),
+		ServerVersion:   string(bytes.TrimRight(body[2:52], "\x00")),
+		CreateTimestamp: binary.LittleEndian.Uint32(body[52:]),
+		HeaderLength:    body[56],
+	}
+	// The checksum algorithm and checksum are appended since MySQL 5.6.1.
+	if parseVersion(format.ServerVersion).atLeast(5, 6, 1) && len(body) >= 57+1+binlogChecksumSize {
+		format.ChecksumAlgorithm = body[len(body)-binlogChecksumSize-1]
+		format.PostHeaderLengths = body[57 : len(body)-binlogChecksumSize-1]
+		if format.ChecksumAlgorithm == binlogChecksumCRC32 && !hasChecksum(data) {
+			return nil, errBinlogChecksum
+		}
+	} else {
+		format.PostHeaderLengths = body[57:]
+	}
+	return format, nil
+}
+
+func decodeQueryEvent(body []byte) (*QueryEvent, error) {
+	if len(body) < 13 {
+		return nil, errBinlogEventShort
+	}
+	event := &QueryEvent{
+		ThreadID:  binary.LittleEndian.Uint32(body),
+		ExecTime:  binary.LittleEndian.Uint32(body[4:]),
+		ErrorCode: binary.LittleEndian.Uint16(body[9:]),
+	}
+	dbLen := int(body[8])
+	statusLen := int(binary.LittleEndian.Uint16(body[11:]))
+	pos := 13 + statusLen
+	if len(body) < pos+dbLen+1 {
+		return nil, errBinlogEventShort
+	}
+	event.Database = string(body[pos : pos+dbLen])
+	event.Query = string(body[pos+dbLen+1:])
+	return event, nil
+}
+
+func decodeGtidEvent(body []byte) (*GtidEvent, error) {
+	if len(body) < 25 {
+		return nil, errBinlogEventShort
+	}
+	sid := body[1:17]
+	uuid := fmt.Sprintf("%x-%x-%x-%x-%x", sid[0:4], sid[4:6], sid[6:8], sid[8:10], sid[10:16])
+	return &GtidEvent{
+		CommitFlag: body[0] != 0,
+		GTID:       uuid + ":" + strconv.FormatUint(binary.LittleEndian.Uint64(body[17:]), 10),
+	}, nil
+}
+
+func (d *BinlogDecoder) decodeTableMapEvent(body []byte) (*TableMapEvent, error) {
+	r := &binlogBuffer{data: body}
+	table := &TableMapEvent{}
+	if d.postHeaderLength(TableMapEventType, 8) == 6 {
+		table.TableID = r.uint(4)
+	} else {
+		table.TableID = r.uint(6)
+	}
+	r.skip(2)
+	table.Schema = string(r.next(int(r.uint(1))))
+	r.skip(1)
+	table.Table = string(r.next(int(r.uint(1))))
+	r.skip(1)
+	columnCount := int(r.lenencInt())
+	table.ColumnTypes = r.next(columnCount)
+	meta := &binlogBuffer{data: r.next(int(r.lenencInt()))}
+	table.ColumnMeta = make([]uint16, columnCount)
+	for i, columnType := range table.ColumnTypes {
+		switch columnType {
+		case mysqlTypeFloat, mysqlTypeDouble, mysqlTypeBlob, mysqlTypeTinyBlob, mysqlTypeMediumBlob,
+			mysqlTypeLongBlob, mysqlTypeGeometry, mysqlTypeJSON,
+			mysqlTypeTimestamp2, mysqlTypeDatetime2, mysqlTypeTime2:
+			table.ColumnMeta[i] = uint16(meta.uint(1))
+		case mysqlTypeVarchar, mysqlTypeVarString, mysqlTypeBit:
+			table.ColumnMeta[i] = uint16(meta.uint(2))
+		case mysqlTypeNewDecimal, mysqlTypeString, mysqlTypeEnum, mysqlTypeSet:
+			// Big-endian: precision and scale, or real type and length.
+			b := meta.next(2)
+			if len(b) == 2 {
+				table.ColumnMeta[i] = uint16(b[0])<<8 | uint16(b[1])
+			}
+		}
+	}
+	table.NullBitmap = r.next((columnCount + 7) / 8)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return table, nil
+}
+
+func (d *BinlogDecoder) decodeRowsEvent(eventType BinlogEventType, body []byte) (*RowsEvent, error) {
+	r := &binlogBuffer{data: body}
+	event := &RowsEvent{}
+	if d.postHeaderLength(eventType, 8) == 6 {
+		event.TableID = r.uint(4)
+	} else {
+		event.TableID = r.uint(6)
+	}
+	event.Flags = uint16(r.uint(2))
+	if eventType >= WriteRowsEventV2Type && eventType <= DeleteRowsEventV2Type {
+		// The extra data length includes itself.
+		r.skip(int(r.uint(2)) - 2)
+	}
+	var exists bool
+	if event.Table, exists = d.tables[event.TableID]; !exists {
+		return nil, errBinlogTableNotMap
+	}
+	columnCount := int(r.lenencInt())
+	present := r.next((columnCount + 7) / 8)
+	isUpdate := eventType == UpdateRowsEventV1Type || eventType == UpdateRowsEventV2Type
+	var afterPresent []byte
+	if isUpdate {
+		afterPresent = r.next((columnCount + 7) / 8)
+	}
+	for r.err == nil && r.pos < len(r.data) {
+		row, err := decodeRow(r, event.Table, columnCount, present)
+		if err != nil {
+			return nil, err
+		}
+		if isUpdate {
+			event.BeforeRows = append(event.BeforeRows, row)
+			if row, err = decodeRow(r, event.Table, columnCount, afterPresent); err != nil {
+				return nil, err
+			}
+		}
+		event.Rows = append(event.Rows, row)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return event, nil
+}
+
+// decodeRow decodes one row image of the columns set in present.
+func decodeRow(r *binlogBuffer, table *TableMapEvent, columnCount int, present []byte) ([]interface{}, error) {
+	if columnCount > len(table.ColumnTypes) {
+		return nil, fmt.Errorf("rows event has %d columns, but table map has %d", columnCount, len(table.ColumnTypes))
+	}
+	presentCount := 0
+	for i := 0; i < columnCount; i++ {
+		if bitSet(present, i) {
+			presentCount++
+		}
+	}
+	nulls := r.next((presentCount + 7) / 8)
+	row := make([]interface{}, columnCount)
+	nullIdx := 0
+	for i := 0; i < columnCount; i++ {
+		if !bitSet(present, i) {
+			continue
+		}
+		isNull := bitSet(nulls, nullIdx)
+		nullIdx++
+		if isNull {
+			continue
+		}
+		value, err := decodeValue(r, table.ColumnTypes[i], table.ColumnMeta[i])
+		if err != nil {
+			return nil, fmt.Errorf("decode column %d of %s.%s: %s", i, table.Schema, table.Table, err.Error())
+		}
+		row[i] = value
+	}
+	return row, r.err
+}
+
+// decodeValue decodes one column value according to its type and metadata.
+func decodeValue(r *binlogBuffer, columnType byte, meta uint16) (interface{}, error) {
+	if columnType == mysqlTypeString && meta >= 256 {
+		realType := byte(meta >> 8)
+		length := int(meta & 0xff)
+		if realType&0x30 != 0x30 {
+			length |= int((realType&0x30)^0x30) << 4
+			realType |= 0x30
+		}
+		switch realType {
+		case mysqlTypeEnum, mysqlTypeSet:
+			return int64(r.uint(length)), nil
+		}
+		if length < 256 {
+			return string(r.next(int(r.uint(1)))), nil
+		}
+		return string(r.next(int(r.uint(2)))), nil
+	}
+
+	switch columnType {
+	case mysqlTypeTiny:
+		return int64(int8(r.uint(1))), nil
+	case mysqlTypeShort:
+		return int64(int16(r.uint(2))), nil
+	case mysqlTypeInt24:
+		v := int64(r.uint(3))
+		if v&0x800000 != 0 {
+			v -= 0x1000000
+		}
+		return v, nil
+	case mysqlTypeLong:
+		return int64(int32(r.uint(4))), nil
+	case mysqlTypeLongLong:
+		return int64(r.uint(8)), nil
+	case mysqlTypeFloat:
+		return math.Float32frombits(uint32(r.uint(4))), nil
+	case mysqlTypeDouble:
+		return math.Float64frombits(r.uint(8)), nil
+	case mysqlTypeYear:
+		if v := r.uint(1); v != 0 {
+			return int64(v + 1900), nil
+		}
+		return int64(0), nil
+	case mysqlTypeDate:
+		v := r.uint(3)
+		return fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)&15, v&31), nil
+	case mysqlTypeVarchar, mysqlTypeVarString:
+		if meta < 256 {
+			return string(r.next(int(r.uint(1)))), nil
+		}
+		return string(r.next(int(r.uint(2)))), nil
+	case mysqlTypeBlob, mysqlTypeTinyBlob, mysqlTypeMediumBlob, mysqlTypeLongBlob, mysqlTypeGeometry, mysqlTypeJSON:
+		return r.next(int(r.uint(int(meta)))), nil
+	case mysqlTypeBit:
+		return r.uintBE(int(meta>>8) + (int(meta&0xff)+7)/8), nil
+	case mysqlTypeNewDecimal:
+		return decodeDecimal(r, int(meta>>8), int(meta&0xff))
+	case mysqlTypeTimestamp:
+		return time.Unix(int64(r.uint(4)), 0).UTC(), nil
+	case mysqlTypeTimestamp2:
+		sec := int64(r.uintBE(4))
+		usec := decodeFraction(r, int(meta))
+		return time.Unix(sec, usec*1000).UTC(), nil
+	case mysqlTypeDatetime:
+		v := r.uint(8)
+		d, t := v/1000000, v%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, d%10000/100, d%100, t/10000, t%10000/100, t%100), nil
+	case mysqlTypeDatetime2:
+		v := int64(r.uintBE(5)) - 0x8000000000
+		usec := decodeFraction(r, int(meta))
+		ymd, hms := v>>17, v%(1<<17)
+		ym := ymd >> 5
+		s := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ym/13, ym%13, ymd%32, hms>>12, (hms>>6)%64, hms%64)
+		return s + formatFraction(usec, int(meta)), nil
+	case mysqlTypeTime:
+		v := r.uint(3)
+		return fmt.Sprintf("%02d:%02d:%02d", v/10000, v%10000/100, v%100), nil
+	case mysqlTypeTime2:
+		return decodeTime2(r, int(meta)), nil
+	}
+	return nil, fmt.Errorf("unsupported column type %d", columnType)
+}
+
+// decodeFraction decodes the fractional seconds of TIMESTAMP2 and DATETIME2 into microseconds.
+func decodeFraction(r *binlogBuffer, fsp int) int64 {
+	switch fsp {
+	case 1, 2:
+		return int64(r.uintBE(1)) * 10000
+	case 3, 4:
+		return int64(r.uintBE(2)) * 100
+	case 5, 6:
+		return int64(r.uintBE(3))
+	}
+	return 0
+}
+
+func formatFraction(usec int64, fsp int) string {
+	if fsp <= 0 {
+		return ""
+	}
+	return "." + fmt.Sprintf("%06d", usec)[:fsp]
+}
+
+// decodeTime2 decodes TIME2, which stores the time as a big-endian signed integer with the fraction.
+func decodeTime2(r *binlogBuffer, fsp int) string {
+	var v int64
+	switch fsp {
+	case 1, 2:
+		intPart := int64(r.uintBE(3)) - 0x800000
+		frac := int64(r.uintBE(1))
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x100
+		}
+		v = intPart<<24 + frac*10000
+	case 3, 4:
+		intPart := int64(r.uintBE(3)) - 0x800000
+		frac := int64(r.uintBE(2))
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		v = intPart<<24 + frac*100
+	case 5, 6:
+		v = int64(r.uintBE(6)) - 0x800000000000
+	default:
+		v = (int64(r.uintBE(3)) - 0x800000) << 24
+	}
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	hms, usec := v>>24, v%(1<<24)
+	return fmt.Sprintf("%s%02d:%02d:%02d", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6)) + formatFraction(usec, fsp)
+}
+
+// decimalCompressedBytes is the bytes used by the leftover digits of a decimal.
+var decimalCompressedBytes = []int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// decodeDecimal decodes the binary format of DECIMAL(precision, scale) into a string.
+func decodeDecimal(r *binlogBuffer, precision, scale int) (string, error) {
+	const digitsPerInteger = 9
+	if precision < 1 || precision > 65 || scale < 0 || scale > 30 || scale > precision {
+		return "", fmt.Errorf("invalid DECIMAL(%d, %d)", precision, scale)
+	}
+	integral := precision - scale
+	uncompIntegral, uncompFractional := integral/digitsPerInteger, scale/digitsPerInteger
+	compIntegral, compFractional := integral-uncompIntegral*digitsPerInteger, scale-uncompFractional*digitsPerInteger
+	size := uncompIntegral*4 + decimalCompressedBytes[compIntegral] + uncompFractional*4 + decimalCompressedBytes[compFractional]
+	buf := append([]byte(nil), r.next(size)...)
+	if r.err != nil {
+		return "", r.err
+	}
+	if len(buf) == 0 {
+		return "", errBinlogEventShort
+	}
+	negative := buf[0]&0x80 == 0
+	buf[0] ^= 0x80
+	if negative {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	d := &binlogBuffer{data: buf}
+	var intDigits, fracDigits bytes.Buffer
+	if n := decimalCompressedBytes[compIntegral]; n > 0 {
+		intDigits.WriteString(strconv.FormatUint(d.uintBE(n), 10))
+	}
+	for i := 0; i < uncompIntegral; i++ {
+		fmt.Fprintf(&intDigits, "%09d", d.uintBE(4))
+	}
+	for i := 0; i < uncompFractional; i++ {
+		fmt.Fprintf(&fracDigits, "%09d", d.uintBE(4))
+	}
+	if n := decimalCompressedBytes[compFractional]; n > 0 {
+		fmt.Fprintf(&fracDigits, "%0*d", compFractional, d.uintBE(n))
+	}
+	s := strings.TrimLeft(intDigits.String(), "0")
+	if s == "" {
+		s = "0"
+	}
+	if scale > 0 {
+		s += "." + fracDigits.String()
+	}
+	if negative {
+		s = "-" + s
+	}
+	return s, nil
+}
+
+func bitSet(bitmap []byte, i int) bool {
+	return i/8 < len(bitmap) && bitmap[i/8]&(1<<uint(i%8)) != 0
+}
+
+// binlogBuffer reads the fields of binlog events. The first error is kept in err,
+// and the following reads return zero values.
+type binlogBuffer struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (b *binlogBuffer) next(n int) []byte {
+	if b.err != nil || n < 0 || b.pos+n > len(b.data) {
+		b.err = errBinlogEventShort
+		return nil
+	}
+	data := b.data[b.pos : b.pos+n]
+	b.pos += n
+	return data
+}
+
+func (b *binlogBuffer) skip(n int) {
+	b.next(n)
+}
+
+// uint reads a little-endian unsigned integer of n bytes.
+func (b *binlogBuffer) uint(n int) uint64 {
+	var v uint64
+	for i, c := range b.next(n) {
+		v |= uint64(c) << (8 * uint(i))
+	}
+	return v
+}
+
+// uintBE reads a big-endian unsigned integer of n bytes.
+func (b *binlogBuffer) uintBE(n int) uint64 {
+	var v uint64
+	for _, c := range b.next(n) {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// lenencInt reads a length-encoded integer.
+func (b *binlogBuffer) lenencInt() uint64 {
+	switch first := b.uint(1); first {
+	case 0xfc:
+		return b.uint(2)
+	case 0xfd:
+		return b.uint(3)
+	case 0xfe:
+		return b.uint(8)
+	default:
+		return first
+	}
+}
+
+// BinlogFileReader reads the events from a binary log file.
+type BinlogFileReader struct {
+	r       io.Reader
+	decoder *BinlogDecoder
+}
+
+// NewBinlogFileReader checks the magic number of the binary log and returns a reader of its events.
+func NewBinlogFileReader(r io.Reader) (*BinlogFileReader, error) {
+	magic := make([]byte, len(binlogFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != binlogFileMagic {
+		return nil, errBinlogMagic
+	}
+	return &BinlogFileReader{r: r, decoder: NewBinlogDecoder()}, nil
+}
+
+// Next reads and decodes the next event. It returns io.EOF at the end of the file.
+func (r *BinlogFileReader) Next() (*ReplicationEvent, error) {
+	header := make([]byte, binlogEventHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, err
+	}
+	size := int(binary.LittleEndian.Uint32(header[9:]))
+	if size < binlogEventHeaderSize {
+		return nil, errBinlogEventShort
+	}
+	data := make([]byte, size)
+	copy(data, header)
+	if _, err := io.ReadFull(r.r, data[binlogEventHeaderSize:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.decoder.Decode(data)
+}
@@ -0,0 +1,296 @@
+package msops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildBinlogEvent builds an event of eventType with the body, appending the CRC32 checksum if checksum is true.
+func buildBinlogEvent(eventType BinlogEventType, body []byte, checksum bool) []byte {
+	size := binlogEventHeaderSize + len(body)
+	if checksum {
+		size += binlogChecksumSize
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(1476460800))
+	buf.WriteByte(byte(eventType))
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(size))
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint16(0))
+	buf.Write(body)
+	if checksum {
+		binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	}
+	return buf.Bytes()
+}
+
+func buildFormatDescription() []byte {
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint16(4))
+	version := make([]byte, 50)
+	copy(version, "5.7.40-log")
+	body.Write(version)
+	binary.Write(&body, binary.LittleEndian, uint32(0))
+	body.WriteByte(binlogEventHeaderSize)
+	postHeaderLengths := make([]byte, 38)
+	postHeaderLengths[TableMapEventType-1] = 8
+	for _, eventType := range []BinlogEventType{WriteRowsEventV2Type, UpdateRowsEventV2Type, DeleteRowsEventV2Type} {
+		postHeaderLengths[eventType-1] = 10
+	}
+	body.Write(postHeaderLengths)
+	body.WriteByte(binlogChecksumCRC32)
+	return buildBinlogEvent(FormatDescriptionEventType, body.Bytes(), true)
+}
+
+// buildTableMap maps table 70 to data_test.tbl_test (id int, name varchar(20), price decimal(10,2), created datetime, qty bigint).
+func buildTableMap() []byte {
+	var body bytes.Buffer
+	body.Write([]byte{70, 0, 0, 0, 0, 0, 1, 0})
+	body.WriteByte(9)
+	body.WriteString("data_test\x00")
+	body.WriteByte(8)
+	body.WriteString("tbl_test\x00")
+	body.WriteByte(5)
+	body.Write([]byte{mysqlTypeLong, mysqlTypeVarchar, mysqlTypeNewDecimal, mysqlTypeDatetime2, mysqlTypeLongLong})
+	body.WriteByte(5)
+	body.Write([]byte{80, 0, 10, 2, 0})
+	body.WriteByte(0x1e)
+	return buildBinlogEvent(TableMapEventType, body.Bytes(), true)
+}
+
+func buildRowsEvent(eventType BinlogEventType, rows ...[]byte) []byte {
+	var body bytes.Buffer
+	body.Write([]byte{70, 0, 0, 0, 0, 0, 1, 0})
+	binary.Write(&body, binary.LittleEndian, uint16(2))
+	body.WriteByte(5)
+	body.WriteByte(0x1f)
+	if eventType == UpdateRowsEventV2Type {
+		body.WriteByte(0x1f)
+	}
+	for _, row := range rows {
+		body.Write(row)
+	}
+	return buildBinlogEvent(eventType, body.Bytes(), true)
+}
+
+// buildRow builds a row image of tbl_test with qty NULL.
+func buildRow(id int32, name string, decimal []byte, datetime []byte) []byte {
+	var row bytes.Buffer
+	row.WriteByte(0x10)
+	binary.Write(&row, binary.LittleEndian, id)
+	row.WriteByte(byte(len(name)))
+	row.WriteString(name)
+	row.Write(decimal)
+	row.Write(datetime)
+	return row.Bytes()
+}
+
+// encodeDatetime2 encodes DATETIME(0) in the binary format.
+func encodeDatetime2(year, month, day, hour, minute, second int64) []byte {
+	ymd := (year*13+month)<<5 | day
+	hms := hour<<12 | minute<<6 | second
+	v := uint64(ymd<<17|hms) + 0x8000000000
+	return []byte{byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func TestBinlogFileReader(t *testing.T) {
+	var file bytes.Buffer
+	file.WriteString(binlogFileMagic)
+	file.Write(buildFormatDescription())
+
+	var query bytes.Buffer
+	query.Write([]byte{7, 0, 0, 0, 0, 0, 0, 0, 9, 0, 0, 0, 0})
+	query.WriteString("data_test\x00BEGIN")
+	file.Write(buildBinlogEvent(QueryEventType, query.Bytes(), true))
+
+	gtid := append([]byte{1}, bytes.Repeat([]byte{0xab}, 16)...)
+	gtid = append(gtid, 42, 0, 0, 0, 0, 0, 0, 0)
+	file.Write(buildBinlogEvent(GtidEventType, gtid, true))
+
+	file.Write(buildTableMap())
+	file.Write(buildRowsEvent(WriteRowsEventV2Type,
+		buildRow(1, "hello", []byte{0x80, 0x00, 0x30, 0x39, 0x43}, encodeDatetime2(2026, 10, 18, 12, 34, 56))))
+	file.Write(buildRowsEvent(UpdateRowsEventV2Type,
+		buildRow(1, "hello", []byte{0x80, 0x00, 0x30, 0x39, 0x43}, encodeDatetime2(2026, 10, 18, 12, 34, 56)),
+		buildRow(1, "world", []byte{0x7f, 0xff, 0xff, 0xfe, 0xcd}, encodeDatetime2(2026, 10, 19, 0, 0, 1))))
+	file.Write(buildBinlogEvent(XidEventType, []byte{99, 0, 0, 0, 0, 0, 0, 0}, true))
+	file.Write(buildBinlogEvent(RotateEventType, append([]byte{4, 0, 0, 0, 0, 0, 0, 0}, "binlog.000002"...), true))
+
+	reader, err := NewBinlogFileReader(&file)
+	if err != nil {
+		t.Fatalf("Test NewBinlogFileReader error: %s", err.Error())
+	}
+	var events []*ReplicationEvent
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Test BinlogFileReader Next error: %s", err.Error())
+		}
+		events = append(events, event)
+	}
+	if len(events) != 8 {
+		t.Fatalf("Test BinlogFileReader failed: actual %d events, expected 8", len(events))
+	}
+
+	if format := events[0].Data.(*FormatDescriptionEvent); format.ServerVersion != "5.7.40-log" ||
+		format.ChecksumAlgorithm != binlogChecksumCRC32 {
+		t.Errorf("Test BinlogFileReader failed: unexpected format description %+v", format)
+	}
+	if query := events[1].Data.(*QueryEvent); query.Database != "data_test" || query.Query != "BEGIN" || query.ThreadID != 7 {
+		t.Errorf("Test BinlogFileReader failed: unexpected query event %+v", query)
+	}
+	if gtid := events[2].Data.(*GtidEvent); gtid.GTID != "abababab-abab-abab-abab-abababababab:42" {
+		t.Errorf("Test BinlogFileReader failed: actual GTID %s", gtid.GTID)
+	}
+	if table := events[3].Data.(*TableMapEvent); table.TableID != 70 || table.Schema != "data_test" ||
+		table.Table != "tbl_test" || !reflect.DeepEqual(table.ColumnMeta, []uint16{0, 80, 0x0a02, 0, 0}) {
+		t.Errorf("Test BinlogFileReader failed: unexpected table map %+v", table)
+	}
+
+	expectedInsert := []interface{}{int64(1), "hello", "12345.67", "2026-10-18 12:34:56", nil}
+	if rows := events[4].Data.(*RowsEvent); len(rows.Rows) != 1 || !reflect.DeepEqual(rows.Rows[0], expectedInsert) {
+		t.Errorf("Test BinlogFileReader failed: actual write rows %v, expected %v", rows.Rows, expectedInsert)
+	}
+	expectedUpdate := []interface{}{int64(1), "world", "-1.50", "2026-10-19 00:00:01", nil}
+	if rows := events[5].Data.(*RowsEvent); len(rows.Rows) != 1 || len(rows.BeforeRows) != 1 ||
+		!reflect.DeepEqual(rows.BeforeRows[0], expectedInsert) || !reflect.DeepEqual(rows.Rows[0], expectedUpdate) {
+		t.Errorf("Test BinlogFileReader failed: actual update rows %v -> %v, expected %v -> %v",
+			rows.BeforeRows, rows.Rows, expectedInsert, expectedUpdate)
+	}
+	if xid := events[6].Data.(*XidEvent); xid.Xid != 99 {
+		t.Errorf("Test BinlogFileReader failed: actual xid %d, expected 99", xid.Xid)
+	}
+	if rotate := events[7].Data.(*RotateEvent); rotate.NextLog != "binlog.000002" || rotate.Position != 4 {
+		t.Errorf("Test BinlogFileReader failed: unexpected rotate event %+v", rotate)
+	}
+}
+
+func TestBinlogDecoderErrors(t *testing.T) {
+	if _, err := NewBinlogFileReader(bytes.NewReader([]byte("not a binlog"))); err != errBinlogMagic {
+		t.Error("Test NewBinlogFileReader with bad magic error: should return errBinlogMagic")
+	}
+	decoder := NewBinlogDecoder()
+	if _, err := decoder.Decode(buildFormatDescription()); err != nil {
+		t.Fatalf("Test BinlogDecoder decode format description error: %s", err.Error())
+	}
+	if _, err := decoder.Decode(buildRowsEvent(WriteRowsEventV2Type)); err != errBinlogTableNotMap {
+		t.Error("Test BinlogDecoder rows event without table map error: should return errBinlogTableNotMap")
+	}
+	corrupted := buildBinlogEvent(XidEventType, []byte{99, 0, 0, 0, 0, 0, 0, 0}, true)
+	corrupted[binlogEventHeaderSize] = 98
+	if _, err := decoder.Decode(corrupted); err != errBinlogChecksum {
+		t.Error("Test BinlogDecoder corrupted event error: should return errBinlogChecksum")
+	}
+}
+
+func TestBinlogDecoderHeaderOnly(t *testing.T) {
+	decoder := NewBinlogDecoder()
+	decoder.HeaderOnly = true
+	if _, err := decoder.Decode(buildFormatDescription()); err != nil {
+		t.Fatalf("Test BinlogDecoder HeaderOnly format description error: %s", err.Error())
+	}
+	// The rows event refers to a table not mapped, which fails unless only the header is decoded.
+	event, err := decoder.Decode(buildRowsEvent(WriteRowsEventV2Type, []byte{0x00}))
+	if err != nil {
+		t.Fatalf("Test BinlogDecoder HeaderOnly rows event error: %s", err.Error())
+	}
+	if event.Header.EventType != WriteRowsEventV2Type || event.Header.Timestamp != 1476460800 || event.Data != nil {
+		t.Errorf("Test BinlogDecoder HeaderOnly failed: unexpected event %+v", event)
+	}
+	// The xid events are still decoded for the transaction boundaries.
+	if event, err = decoder.Decode(buildBinlogEvent(XidEventType, []byte{99, 0, 0, 0, 0, 0, 0, 0}, true)); err != nil {
+		t.Fatalf("Test BinlogDecoder HeaderOnly xid event error: %s", err.Error())
+	}
+	if xid, ok := event.Data.(*XidEvent); !ok || xid.Xid != 99 {
+		t.Errorf("Test BinlogDecoder HeaderOnly failed: unexpected xid event %+v", event)
+	}
+}
+
+// TestBinlogFixtures decodes the binary logs of testdata/binlog/capture.sql in the formats of MySQL 5.6, 5.7 and 8.0:
+// generated-<version>.bin are written by testdata/binlog/generate.go, and mysql-<version>.bin are captured
+// from the real servers by testdata/binlog/capture.sh.
+func TestBinlogFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "binlog", "*.bin"))
+	if err != nil {
+		t.Fatalf("Test BinlogFixtures error: %s", err.Error())
+	}
+	if len(files) < 3 {
+		t.Fatalf("Test BinlogFixtures failed: actual %d binlog fixtures, expected at least 3", len(files))
+	}
+
+	inserted := []interface{}{int64(-128), int64(-32768), int64(-8388608), int64(-2147483648), int64(-9223372036854775808),
+		float32(1.5), float64(-2.25), "-12345678901234.567890", int64(2026), "2026-10-18",
+		"-12:34:56.789", "2026-10-18 12:34:56.123456", time.Date(2026, 10, 18, 12, 34, 56, 0, time.UTC),
+		"char", strings.Repeat("v", 300), []byte("blob"), []byte("text"), uint64(682), int64(2), int64(5), nil}
+	updated := append([]interface{}(nil), inserted...)
+	updated[3], updated[7] = int64(42), "0.500000"
+
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("Test BinlogFixtures %s error: %s", file, err.Error())
+		}
+		reader, err := NewBinlogFileReader(f)
+		if err != nil {
+			f.Close()
+			t.Fatalf("Test BinlogFixtures %s error: %s", file, err.Error())
+		}
+		var rowsEvents []*RowsEvent
+		var checksum bool
+		for {
+			event, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Test BinlogFixtures %s Next error: %s", file, err.Error())
+			}
+			switch data := event.Data.(type) {
+			case *FormatDescriptionEvent:
+				checksum = data.ChecksumAlgorithm == binlogChecksumCRC32
+			case *RowsEvent:
+				rowsEvents = append(rowsEvents, data)
+			}
+		}
+		f.Close()
+
+		if !checksum {
+			t.Errorf("Test BinlogFixtures %s failed: the binlog checksum is not enabled", file)
+		}
+		if len(rowsEvents) != 3 {
+			t.Fatalf("Test BinlogFixtures %s failed: actual %d rows events, expected 3", file, len(rowsEvents))
+		}
+		if rows := rowsEvents[0].Rows; len(rows) != 1 || !reflect.DeepEqual(rows[0], inserted) {
+			t.Errorf("Test BinlogFixtures %s failed: actual write rows %v, expected %v", file, rows, inserted)
+		}
+		if rows := rowsEvents[1]; len(rows.Rows) != 1 || len(rows.BeforeRows) != 1 ||
+			!reflect.DeepEqual(rows.BeforeRows[0], inserted) || !reflect.DeepEqual(rows.Rows[0], updated) {
+			t.Errorf("Test BinlogFixtures %s failed: actual update rows %v -> %v, expected %v -> %v",
+				file, rows.BeforeRows, rows.Rows, inserted, updated)
+		}
+		if rows := rowsEvents[2].Rows; len(rows) != 1 || !reflect.DeepEqual(rows[0], updated) {
+			t.Errorf("Test BinlogFixtures %s failed: actual delete rows %v, expected %v", file, rows, updated)
+		}
+	}
+}
+
+func TestDecodeDecimalInvalid(t *testing.T) {
+	for _, c := range [][2]int{{0, 0}, {66, 0}, {10, 31}, {2, 5}} {
+		if _, err := decodeDecimal(&binlogBuffer{data: make([]byte, 32)}, c[0], c[1]); err == nil {
+			t.Errorf("Test decodeDecimal DECIMAL(%d, %d) error: should return error", c[0], c[1])
+		}
+	}
+	if _, err := decodeDecimal(&binlogBuffer{}, 10, 2); err != errBinlogEventShort {
+		t.Error("Test decodeDecimal with empty data error: should return errBinlogEventShort")
+	}
+}
@@ -0,0 +1,399 @@
+package msops
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// BinlogStreamOptions configures the replication connection of BinlogStreamer.
+type BinlogStreamOptions struct {
+	// ServerID is the server_id the streamer registers as. It must be unique in the replication topology.
+	// If it's 0, the streamer doesn't register as a replica, as mysqlbinlog does, which requires NonBlock.
+	ServerID uint32
+
+	// File and Position are the binlog coordinates to start from.
+	// The first binary log of the master is used if File is empty.
+	File     string
+	Position uint32
+
+	// NonBlock makes Next return io.EOF when all the binary logs are read,
+	// instead of waiting for new events.
+	NonBlock bool
+
+	// Timeout is the timeout of connecting to the master, 10 seconds by default.
+	Timeout time.Duration
+
+	// HeaderOnly decodes the headers of the events only, see BinlogDecoder.
+	HeaderOnly bool
+}
+
+// BinlogStreamer reads the binlog events from a master with the replication protocol, as a replica does.
+type BinlogStreamer struct {
+	conn    net.Conn
+	seq     byte
+	decoder *BinlogDecoder
+}
+
+// Capability flags and commands of the MySQL client/server protocol.
+const (
+	clientLongPassword     = 0x00000001
+	clientLongFlag         = 0x00000004
+	clientProtocol41       = 0x00000200
+	clientTransactions     = 0x00002000
+	clientSecureConnection = 0x00008000
+	clientPluginAuth       = 0x00080000
+
+	comQuery           = 0x03
+	comBinlogDump      = 0x12
+	comRegisterSlave   = 0x15
+	binlogDumpNonBlock = 0x01
+
+	maxPacketSize = 1<<24 - 1
+
+	nativePasswordPlugin      = "mysql_native_password"
+	cachingSha2PasswordPlugin = "caching_sha2_password"
+)
+
+var (
+	errProtocolUnsupported = errors.New("the protocol version of server is not supported")
+	errMalformedPacket     = errors.New("malformed packet from server")
+	errAuthUnsupported     = errors.New("the authentication requires TLS or RSA which is not supported")
+)
+
+// StartBinlogStream connects to the master with the replUser and replPassword of the registered master,
+// registers as a replica with opts.ServerID and requests the binlog events from the coordinates of opts.
+//
+// Only mysql_native_password and the fast path of caching_sha2_password authentication are supported.
+func StartBinlogStream(masterEndpoint string, opts BinlogStreamOptions) (*BinlogStreamer, error) {
+	var inst *Instance
+	var exists bool
+	if inst, exists = getInstance(masterEndpoint); !exists {
+		return nil, errNotRegistered
+	}
+	if opts.ServerID == 0 && !opts.NonBlock {
+		return nil, errServerIDRequired
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", masterEndpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+	s := &BinlogStreamer{conn: conn, decoder: NewBinlogDecoder()}
+	s.decoder.HeaderOnly = opts.HeaderOnly
+	if err = s.handshake(inst.replUser, inst.replPassword); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The master sends events with checksum only if the replica declares that it supports checksum.
+	// Servers before MySQL 5.6 return an error of unknown variable, which is ignored.
+	if err = s.command(comQuery, []byte("SET @master_binlog_checksum = @@global.binlog_checksum")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err = s.readResult(); err != nil {
+		if _, isServerErr := err.(*serverError); !isServerErr {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if opts.ServerID != 0 {
+		if err = s.register(inst, opts.ServerID); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err = s.dump(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Next reads and decodes the next binlog event. Under non-block mode, it returns io.EOF after the last event.
+func (s *BinlogStreamer) Next() (*ReplicationEvent, error) {
+	data, err := s.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(data) == 0:
+		return nil, errMalformedPacket
+	case data[0] == 0xff:
+		return nil, parseServerError(data)
+	case data[0] == 0xfe && len(data) < 9:
+		return nil, io.EOF
+	case data[0] != 0x00:
+		return nil, errMalformedPacket
+	}
+	return s.decoder.Decode(data[1:])
+}
+
+// Close closes the connection to the master.
+func (s *BinlogStreamer) Close() error {
+	return s.conn.Close()
+}
+
+// serverError represents an ERR packet from server.
+type serverError struct {
+	Code    uint16
+	Message string
+}
+
+func (e *serverError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Code, e.Message)
+}
+
+func parseServerError(data []byte) error {
+	if len(data) < 3 {
+		return errMalformedPacket
+	}
+	e := &serverError{Code: binary.LittleEndian.Uint16(data[1:])}
+	msg := data[3:]
+	if len(msg) > 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	e.Message = string(msg)
+	return e
+}
+
+func (s *BinlogStreamer) handshake(user, password string) error {
+	data, err := s.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[0] == 0xff {
+		return parseServerError(data)
+	}
+	// Protocol::HandshakeV10
+	r := &binlogBuffer{data: data}
+	if r.uint(1) != 10 {
+		return errProtocolUnsupported
+	}
+	if idx := bytes.IndexByte(data[1:], 0); idx >= 0 {
+		r.skip(idx + 1)
+	}
+	r.skip(4)
+	scramble := append([]byte(nil), r.next(8)...)
+	r.skip(1)
+	capabilities := uint32(r.uint(2))
+	r.skip(3)
+	capabilities |= uint32(r.uint(2)) << 16
+	authDataLen := int(r.uint(1))
+	r.skip(10)
+	plugin := nativePasswordPlugin
+	if capabilities&clientSecureConnection != 0 {
+		n := authDataLen - 8
+		if n < 13 {
+			n = 13
+		}
+		scramble = append(scramble, r.next(n-1)...)
+		r.skip(1)
+	}
+	if capabilities&clientPluginAuth != 0 && r.err == nil {
+		rest := r.data[r.pos:]
+		if idx := bytes.IndexByte(rest, 0); idx >= 0 {
+			rest = rest[:idx]
+		}
+		if len(rest) > 0 {
+			plugin = string(rest)
+		}
+	}
+	if r.err != nil {
+		return errMalformedPacket
+	}
+	if plugin != cachingSha2PasswordPlugin {
+		plugin = nativePasswordPlugin
+	}
+	authData := scramblePassword(plugin, scramble, password)
+
+	// Protocol::HandshakeResponse41
+	var resp bytes.Buffer
+	binary.Write(&resp, binary.LittleEndian, uint32(clientLongPassword|clientLongFlag|clientProtocol41|
+		clientTransactions|clientSecureConnection|clientPluginAuth))
+	binary.Write(&resp, binary.LittleEndian, uint32(maxPacketSize))
+	resp.WriteByte(33)
+	resp.Write(make([]byte, 23))
+	resp.WriteString(user)
+	resp.WriteByte(0)
+	resp.WriteByte(byte(len(authData)))
+	resp.Write(authData)
+	resp.WriteString(plugin)
+	resp.WriteByte(0)
+	if err = s.writePacket(resp.Bytes()); err != nil {
+		return err
+	}
+	return s.authResult(plugin, scramble, password)
+}
+
+// authResult handles the responses of authentication until OK or ERR is received.
+func (s *BinlogStreamer) authResult(plugin string, scramble []byte, password string) error {
+	for {
+		data, err := s.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return errMalformedPacket
+		}
+		switch data[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return parseServerError(data)
+		case 0xfe:
+			// AuthSwitchRequest
+			rest := data[1:]
+			idx := bytes.IndexByte(rest, 0)
+			if idx < 0 {
+				return errMalformedPacket
+			}
+			plugin = string(rest[:idx])
+			scramble = bytes.TrimRight(rest[idx+1:], "\x00")
+			if plugin != nativePasswordPlugin && plugin != cachingSha2PasswordPlugin {
+				return fmt.Errorf("the authentication plugin %s is not supported", plugin)
+			}
+			if err = s.writePacket(scramblePassword(plugin, scramble, password)); err != nil {
+				return err
+			}
+		case 0x01:
+			// AuthMoreData of caching_sha2_password: 3 is fast auth success, 4 is full auth required.
+			if plugin != cachingSha2PasswordPlugin || len(data) < 2 || data[1] != 3 {
+				return errAuthUnsupported
+			}
+		default:
+			return errMalformedPacket
+		}
+	}
+}
+
+// scramblePassword computes the auth response of the plugin.
+func scramblePassword(plugin string, scramble []byte, password string) []byte {
+	if password == "" {
+		return nil
+	}
+	if plugin == cachingSha2PasswordPlugin {
+		// XOR(SHA256(password), SHA256(SHA256(SHA256(password)), scramble))
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:]
+	}
+	// XOR(SHA1(password), SHA1(scramble, SHA1(SHA1(password))))
+	h1 := sha1.Sum([]byte(password))
+	h2 := sha1.Sum(h1[:])
+	h3 := sha1.Sum(append(append([]byte(nil), scramble...), h2[:]...))
+	for i := range h1 {
+		h1[i] ^= h3[i]
+	}
+	return h1[:]
+}
+
+// register sends COM_REGISTER_SLAVE.
+func (s *BinlogStreamer) register(inst *Instance, serverID uint32) error {
+	hostname, _ := os.Hostname()
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, serverID)
+	for _, field := range []string{hostname, inst.replUser, inst.replPassword} {
+		if len(field) > 255 {
+			field = field[:255]
+		}
+		data.WriteByte(byte(len(field)))
+		data.WriteString(field)
+	}
+	binary.Write(&data, binary.LittleEndian, uint16(0))
+	binary.Write(&data, binary.LittleEndian, uint32(0))
+	binary.Write(&data, binary.LittleEndian, uint32(0))
+	if err := s.command(comRegisterSlave, data.Bytes()); err != nil {
+		return err
+	}
+	_, err := s.readResult()
+	return err
+}
+
+// dump sends COM_BINLOG_DUMP.
+func (s *BinlogStreamer) dump(opts BinlogStreamOptions) error {
+	pos := opts.Position
+	if pos < uint32(len(binlogFileMagic)) {
+		pos = uint32(len(binlogFileMagic))
+	}
+	var flags uint16
+	if opts.NonBlock {
+		flags |= binlogDumpNonBlock
+	}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, pos)
+	binary.Write(&data, binary.LittleEndian, flags)
+	binary.Write(&data, binary.LittleEndian, opts.ServerID)
+	data.WriteString(opts.File)
+	return s.command(comBinlogDump, data.Bytes())
+}
+
+// command sends a command packet with a new sequence.
+func (s *BinlogStreamer) command(cmd byte, data []byte) error {
+	s.seq = 0
+	return s.writePacket(append([]byte{cmd}, data...))
+}
+
+// readResult reads an OK or ERR packet.
+func (s *BinlogStreamer) readResult() ([]byte, error) {
+	data, err := s.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 && data[0] == 0xff {
+		return nil, parseServerError(data)
+	}
+	return data, nil
+}
+
+func (s *BinlogStreamer) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(s.conn, header); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		s.seq = header[3] + 1
+		data := make([]byte, length)
+		if _, err := io.ReadFull(s.conn, data); err != nil {
+			return nil, err
+		}
+		payload = append(payload, data...)
+		if length < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+func (s *BinlogStreamer) writePacket(data []byte) error {
+	for {
+		length := len(data)
+		if length > maxPacketSize {
+			length = maxPacketSize
+		}
+		header := []byte{byte(length), byte(length >> 8), byte(length >> 16), s.seq}
+		s.seq++
+		if _, err := s.conn.Write(append(header, data[:length]...)); err != nil {
+			return err
+		}
+		data = data[length:]
+		if length < maxPacketSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package msops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// serveFakeMaster accepts one connection and plays the master side of the replication protocol,
+// sending events after COM_BINLOG_DUMP and then an EOF packet.
+func serveFakeMaster(t *testing.T, listener net.Listener, password string, events [][]byte) {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Errorf("Fake master accept error: %s", err.Error())
+		return
+	}
+	defer conn.Close()
+	s := &BinlogStreamer{conn: conn}
+	scramble := []byte("abcdefghijklmnopqrst")
+
+	var handshake bytes.Buffer
+	handshake.WriteByte(10)
+	handshake.WriteString("5.7.40-log\x00")
+	binary.Write(&handshake, binary.LittleEndian, uint32(1))
+	handshake.Write(scramble[:8])
+	handshake.WriteByte(0)
+	binary.Write(&handshake, binary.LittleEndian, uint16(clientProtocol41|clientSecureConnection))
+	handshake.WriteByte(33)
+	binary.Write(&handshake, binary.LittleEndian, uint16(0))
+	binary.Write(&handshake, binary.LittleEndian, uint16(clientPluginAuth>>16))
+	handshake.WriteByte(21)
+	handshake.Write(make([]byte, 10))
+	handshake.Write(scramble[8:])
+	handshake.WriteByte(0)
+	handshake.WriteString(nativePasswordPlugin + "\x00")
+	s.writePacket(handshake.Bytes())
+
+	resp, err := s.readPacket()
+	if err != nil {
+		t.Errorf("Fake master read handshake response error: %s", err.Error())
+		return
+	}
+	if !bytes.Contains(resp, scramblePassword(nativePasswordPlugin, scramble, password)) {
+		s.writePacket([]byte("\xff\x15\x04#28000Access denied"))
+		return
+	}
+	s.writePacket([]byte{0, 0, 0, 2, 0, 0, 0})
+
+	for _, expected := range []byte{comQuery, comRegisterSlave, comBinlogDump} {
+		s.seq = 0
+		cmd, err := s.readPacket()
+		if err != nil || len(cmd) == 0 || cmd[0] != expected {
+			t.Errorf("Fake master failed: actual command %v, expected %d", cmd, expected)
+			return
+		}
+		if expected != comBinlogDump {
+			s.writePacket([]byte{0, 0, 0, 2, 0, 0, 0})
+		}
+	}
+	for _, event := range events {
+		s.writePacket(append([]byte{0}, event...))
+	}
+	s.writePacket([]byte{0xfe, 0, 0, 2, 0})
+}
+
+func TestBinlogStreamerWithFakeMaster(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Test BinlogStreamer listen error: %s", err.Error())
+	}
+	defer listener.Close()
+	endpoint := listener.Addr().String()
+	Register(endpoint, testDBAUser, testDBAPass, testReplUser, testReplPass, nil)
+	defer Unregister(endpoint)
+	go serveFakeMaster(t, listener, testReplPass, [][]byte{
+		buildBinlogEvent(RotateEventType, append([]byte{4, 0, 0, 0, 0, 0, 0, 0}, "binlog.000001"...), true),
+		buildFormatDescription(),
+		buildTableMap(),
+	})
+
+	streamer, err := StartBinlogStream(endpoint, BinlogStreamOptions{ServerID: 100, NonBlock: true})
+	if err != nil {
+		t.Fatalf("Test StartBinlogStream error: %s", err.Error())
+	}
+	defer streamer.Close()
+	var eventTypes []BinlogEventType
+	for {
+		event, err := streamer.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Test BinlogStreamer Next error: %s", err.Error())
+		}
+		eventTypes = append(eventTypes, event.Header.EventType)
+		if rotate, ok := event.Data.(*RotateEvent); ok && rotate.NextLog != "binlog.000001" {
+			t.Errorf("Test BinlogStreamer failed: actual rotate to %s, expected binlog.000001", rotate.NextLog)
+		}
+	}
+	if len(eventTypes) != 3 || eventTypes[2] != TableMapEventType {
+		t.Errorf("Test BinlogStreamer failed: actual event types %v", eventTypes)
+	}
+}
+
+func TestStartBinlogStream(t *testing.T) {
+	if _, err := StartBinlogStream(unregisteredEndpoint, BinlogStreamOptions{ServerID: 100}); err != errNotRegistered {
+		t.Error("Test StartBinlogStream unregisteredEndpoint error: should return errNotRegistered")
+	}
+
+	// Register testEndpoint1 with another name whose replication user is the dba user.
+	endpoint := "localhost:3301"
+	Register(endpoint, testDBAUser, testDBAPass, testDBAUser, testDBAPass, nil)
+	defer Unregister(endpoint)
+	streamer, err := StartBinlogStream(endpoint, BinlogStreamOptions{ServerID: 100, NonBlock: true})
+	if err != nil {
+		t.Fatalf("Test StartBinlogStream error: %s", err.Error())
+	}
+	defer streamer.Close()
+	var formatFound bool
+	for {
+		event, err := streamer.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Test BinlogStreamer Next error: %s", err.Error())
+		}
+		if _, ok := event.Data.(*FormatDescriptionEvent); ok {
+			formatFound = true
+		}
+	}
+	if !formatFound {
+		t.Error("Test StartBinlogStream failed: format description event is not found")
+	}
+}
@@ -0,0 +1,221 @@
+package msops
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMasterDelay is the maximum value of MASTER_DELAY.
+const maxMasterDelay = 1<<31 - 1
+
+// RewindOptions describes where RewindDelayedReplica stops the delayed replica.
+// Exactly one of GTID and Timestamp must be set.
+type RewindOptions struct {
+	// GTID is the GTID set of the accidental transactions, before which the SQL thread stops.
+	GTID string
+
+	// Timestamp makes the SQL thread stop before the first transaction logged at or after it on the master.
+	// The binary logs of the master are streamed with ServerID to find the position of that transaction.
+	Timestamp time.Time
+	ServerID  uint32
+
+	// CatchUp sets MASTER_DELAY to 0 before restarting the SQL thread,
+	// so that the replica applies the transactions up to the stop point immediately.
+	CatchUp bool
+}
+
+// RewindResult records the condition of "START SLAVE SQL_THREAD UNTIL" executed by RewindDelayedReplica.
+// Either UntilGTID or UntilLogFile and UntilLogPos are set.
+type RewindResult struct {
+	UntilGTID    string
+	UntilLogFile string
+	UntilLogPos  int
+}
+
+var (
+	errDelayInvalid     = errors.New("the delay is out of range")
+	errNotDelayed       = errors.New("the replica is not delayed")
+	errRewindTarget     = errors.New("exactly one of GTID and timestamp should be specified")
+	errServerIDRequired = errors.New("the server id is required to stream binary logs")
+	errRewindPassed     = errors.New("the replica has already applied the target")
+	errRewindNotFound   = errors.New("no transaction is found after the timestamp")
+)
+
+// SetMasterDelay sets MASTER_DELAY of the slave to delay seconds.
+// The SQL thread is stopped during the change and restarted if it was running.
+// Before MySQL 5.7.4 the I/O thread is stopped and restarted as well, see changeMasterDelay.
+func SetMasterDelay(slaveEndpoint string, delay int) error {
+	var slaveStatus SlaveStatus
+	var err error
+	if delay < 0 || delay > maxMasterDelay {
+		return errDelayInvalid
+	}
+	if slaveStatus, err = GetSlaveStatus(slaveEndpoint); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(emptySlaveStatus, slaveStatus) {
+		return errNotSlave
+	}
+	running := slaveStatus.SlaveSQLRunning == "Yes"
+	if running {
+		if err = execute(slaveEndpoint, "STOP SLAVE SQL_THREAD"); err != nil {
+			return err
+		}
+	}
+	if err = changeMasterDelay(slaveEndpoint, delay, slaveStatus.SlaveIORunning != "No"); err != nil {
+		return err
+	}
+	if running {
+		return execute(slaveEndpoint, "START SLAVE SQL_THREAD")
+	}
+	return nil
+}
+
+// RewindDelayedReplica stops the SQL thread of the delayed replica and restarts it with an UNTIL condition,
+// so that it stops just before the transaction described by opts, e.g. an accidental "DROP TABLE".
+//
+// In timestamp mode the master of the replica must be registered.
+//
+// The SQL thread is left stopped if an error occurs after it's stopped, so that the target is never applied.
+func RewindDelayedReplica(slaveEndpoint string, opts RewindOptions) (RewindResult, error) {
+	var result RewindResult
+	var slaveStatus SlaveStatus
+	var err error
+	if (opts.GTID == "") == opts.Timestamp.IsZero() {
+		return result, errRewindTarget
+	}
+	if !opts.Timestamp.IsZero() && opts.ServerID == 0 {
+		return result, errServerIDRequired
+	}
+	if slaveStatus, err = GetSlaveStatus(slaveEndpoint); err != nil {
+		return result, err
+	}
+	if reflect.DeepEqual(emptySlaveStatus, slaveStatus) {
+		return result, errNotSlave
+	}
+	if slaveStatus.SQLDelay == 0 {
+		return result, errNotDelayed
+	}
+	if err = execute(slaveEndpoint, "STOP SLAVE SQL_THREAD"); err != nil {
+		return result, err
+	}
+	// Read the status again for the coordinates at which the SQL thread stops.
+	if slaveStatus, err = GetSlaveStatus(slaveEndpoint); err != nil {
+		return result, err
+	}
+
+	if opts.GTID != "" {
+		var target, executed gtidSet
+		if target, err = parseGtidSet(opts.GTID); err != nil {
+			return result, err
+		}
+		if executed, err = parseGtidSet(slaveStatus.ExecutedGtidSet); err != nil {
+			return result, err
+		}
+		for uuid, intervals := range target {
+			for _, interval := range intervals {
+				if executed.contains(uuid, interval.start) {
+					return result, errRewindPassed
+				}
+			}
+		}
+		result.UntilGTID = opts.GTID
+	} else {
+		masterEndpoint := net.JoinHostPort(slaveStatus.MasterHost, strconv.Itoa(slaveStatus.MasterPort))
+		streamer, e := StartBinlogStream(masterEndpoint, BinlogStreamOptions{
+			ServerID: opts.ServerID,
+			File:     slaveStatus.RelayMasterLogFile,
+			Position: uint32(slaveStatus.ExecMasterLogPos),
+			NonBlock: true,
+			// Only the positions and the transaction boundaries are needed, so the rows are not decoded.
+			HeaderOnly: true,
+		})
+		if e != nil {
+			return result, e
+		}
+		file, pos, e := findTransactionAfter(streamer.Next, slaveStatus.RelayMasterLogFile,
+			uint32(slaveStatus.ExecMasterLogPos), opts.Timestamp)
+		streamer.Close()
+		if e != nil {
+			return result, e
+		}
+		result.UntilLogFile, result.UntilLogPos = file, int(pos)
+	}
+
+	if opts.CatchUp {
+		if err = changeMasterDelay(slaveEndpoint, 0, slaveStatus.SlaveIORunning != "No"); err != nil {
+			return result, err
+		}
+	}
+	if result.UntilGTID != "" {
+		return result, execute(slaveEndpoint, "START SLAVE SQL_THREAD UNTIL SQL_BEFORE_GTIDS=?", result.UntilGTID)
+	}
+	return result, execute(slaveEndpoint, "START SLAVE SQL_THREAD UNTIL MASTER_LOG_FILE=?, MASTER_LOG_POS=?",
+		result.UntilLogFile, result.UntilLogPos)
+}
+
+// changeMasterDelay executes "CHANGE MASTER TO MASTER_DELAY" on the slave whose SQL thread is stopped.
+//
+// Before MySQL 5.7.4 the I/O thread must be stopped too, which is restarted if ioRunning.
+// Note that CHANGE MASTER TO purges the relay logs then, so the I/O thread fetches the events
+// from the master again since the SQL thread's position.
+func changeMasterDelay(slaveEndpoint string, delay int, ioRunning bool) error {
+	version, err := getVersion(slaveEndpoint)
+	if err != nil {
+		return err
+	}
+	if version.atLeast(5, 7, 4) || !ioRunning {
+		return execute(slaveEndpoint, "CHANGE MASTER TO MASTER_DELAY=?", delay)
+	}
+	if err = execute(slaveEndpoint, "STOP SLAVE IO_THREAD"); err != nil {
+		return err
+	}
+	if err = execute(slaveEndpoint, "CHANGE MASTER TO MASTER_DELAY=?", delay); err != nil {
+		return err
+	}
+	return execute(slaveEndpoint, "START SLAVE IO_THREAD")
+}
+
+// findTransactionAfter reads the events from the coordinates file and pos with next,
+// and returns the start coordinates of the first transaction logged at or after until.
+func findTransactionAfter(next func() (*ReplicationEvent, error), file string, pos uint32, until time.Time) (string, uint32, error) {
+	// groupStart is the end of the last transaction, i.e. the start of the next one.
+	groupStart := pos
+	for {
+		event, err := next()
+		if err == io.EOF {
+			return "", 0, errRewindNotFound
+		} else if err != nil {
+			return "", 0, err
+		}
+		switch data := event.Data.(type) {
+		case *RotateEvent:
+			file, groupStart = data.NextLog, uint32(data.Position)
+			continue
+		case *FormatDescriptionEvent:
+			continue
+		}
+		// Artificial events and heartbeats have no position in the binary log.
+		if event.Header.LogPos == 0 || event.Header.EventType == HeartbeatEventType ||
+			event.Header.EventType == PreviousGtidsEventType {
+			continue
+		}
+		if int64(event.Header.Timestamp) >= until.Unix() {
+			return file, groupStart, nil
+		}
+		switch data := event.Data.(type) {
+		case *XidEvent:
+			groupStart = event.Header.LogPos
+		case *QueryEvent:
+			// A query other than BEGIN ends the transaction, e.g. COMMIT of non-transactional tables or DDL.
+			if !strings.EqualFold(data.Query, "BEGIN") {
+				groupStart = event.Header.LogPos
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package msops
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFindTransactionAfter(t *testing.T) {
+	newEvent := func(eventType BinlogEventType, timestamp, logPos uint32, data interface{}) *ReplicationEvent {
+		return &ReplicationEvent{
+			Header: BinlogEventHeader{Timestamp: timestamp, EventType: eventType, LogPos: logPos},
+			Data:   data,
+		}
+	}
+	events := []*ReplicationEvent{
+		newEvent(RotateEventType, 0, 0, &RotateEvent{Position: 120, NextLog: "binlog.000001"}),
+		newEvent(FormatDescriptionEventType, 100, 0, &FormatDescriptionEvent{}),
+		newEvent(QueryEventType, 100, 200, &QueryEvent{Query: "BEGIN"}),
+		newEvent(XidEventType, 100, 231, &XidEvent{Xid: 1}),
+		newEvent(QueryEventType, 150, 350, &QueryEvent{Query: "CREATE TABLE t (id int)"}),
+		newEvent(RotateEventType, 150, 400, &RotateEvent{Position: 4, NextLog: "binlog.000002"}),
+		newEvent(QueryEventType, 190, 200, &QueryEvent{Query: "BEGIN"}),
+		newEvent(HeartbeatEventType, 300, 0, nil),
+		newEvent(QueryEventType, 200, 300, &QueryEvent{Query: "DROP TABLE t"}),
+	}
+	next := func(until int64) (string, uint32, error) {
+		i := 0
+		return findTransactionAfter(func() (*ReplicationEvent, error) {
+			if i == len(events) {
+				return nil, io.EOF
+			}
+			i++
+			return events[i-1], nil
+		}, "binlog.000001", 120, time.Unix(until, 0))
+	}
+
+	if file, pos, err := next(150); err != nil {
+		t.Errorf("Test findTransactionAfter error: %s", err.Error())
+	} else if file != "binlog.000001" || pos != 231 {
+		t.Errorf("Test findTransactionAfter failed: actual %s:%d, expected binlog.000001:231", file, pos)
+	}
+	if file, pos, err := next(195); err != nil {
+		t.Errorf("Test findTransactionAfter error: %s", err.Error())
+	} else if file != "binlog.000002" || pos != 4 {
+		t.Errorf("Test findTransactionAfter inside transaction failed: actual %s:%d, expected binlog.000002:4", file, pos)
+	}
+	if _, _, err := next(500); err != errRewindNotFound {
+		t.Error("Test findTransactionAfter after last event error: should return errRewindNotFound")
+	}
+}
+
+func TestSetMasterDelay(t *testing.T) {
+	if SetMasterDelay(testEndpoint3, -1) != errDelayInvalid {
+		t.Error("Test SetMasterDelay negative delay error: should return errDelayInvalid")
+	}
+	if SetMasterDelay(unregisteredEndpoint, 3600) != errNotRegistered {
+		t.Error("Test SetMasterDelay unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if SetMasterDelay(testEndpoint1, 3600) != errNotSlave {
+		t.Error("Test SetMasterDelay testEndpoint1 error: should return errNotSlave")
+	}
+
+	if err := ChangeMasterTo(testEndpoint3, testEndpoint1, false); err != nil {
+		t.Fatalf("Test SetMasterDelay ChangeMasterTo error: %s", err.Error())
+	}
+	defer ResetSlave(testEndpoint3, true)
+	if _, err := RewindDelayedReplica(testEndpoint3, RewindOptions{GTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1"}); err != errNotDelayed {
+		t.Error("Test RewindDelayedReplica without delay error: should return errNotDelayed")
+	}
+	if err := SetMasterDelay(testEndpoint3, 3600); err != nil {
+		t.Errorf("Test SetMasterDelay error: %s", err.Error())
+	} else if slaveSt, err := GetSlaveStatus(testEndpoint3); err != nil {
+		t.Errorf("Test SetMasterDelay GetSlaveStatus error: %s", err.Error())
+	} else if slaveSt.SQLDelay != 3600 {
+		t.Errorf("Test SetMasterDelay failed: actual SQL delay %d, expected 3600", slaveSt.SQLDelay)
+	}
+
+	// Both threads are running, which requires stopping the I/O thread as well before MySQL 5.7.4.
+	if err := StartSlave(testEndpoint3); err != nil {
+		t.Fatalf("Test SetMasterDelay StartSlave error: %s", err.Error())
+	}
+	defer StopSlave(testEndpoint3)
+	if err := SetMasterDelay(testEndpoint3, 0); err != nil {
+		t.Errorf("Test SetMasterDelay running slave error: %s", err.Error())
+	} else if slaveSt, err := GetSlaveStatus(testEndpoint3); err != nil {
+		t.Errorf("Test SetMasterDelay GetSlaveStatus error: %s", err.Error())
+	} else if slaveSt.SQLDelay != 0 || slaveSt.SlaveSQLRunning != "Yes" || slaveSt.SlaveIORunning == "No" {
+		t.Errorf("Test SetMasterDelay running slave failed: unexpected slave status %+v", slaveSt)
+	}
+}
+
+func TestRewindDelayedReplica(t *testing.T) {
+	if _, err := RewindDelayedReplica(testEndpoint3, RewindOptions{}); err != errRewindTarget {
+		t.Error("Test RewindDelayedReplica without target error: should return errRewindTarget")
+	}
+	opts := RewindOptions{GTID: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1", Timestamp: time.Now()}
+	if _, err := RewindDelayedReplica(testEndpoint3, opts); err != errRewindTarget {
+		t.Error("Test RewindDelayedReplica with both targets error: should return errRewindTarget")
+	}
+	if _, err := RewindDelayedReplica(testEndpoint3, RewindOptions{Timestamp: time.Now()}); err != errServerIDRequired {
+		t.Error("Test RewindDelayedReplica without server id error: should return errServerIDRequired")
+	}
+	if _, err := RewindDelayedReplica(unregisteredEndpoint, RewindOptions{Timestamp: time.Now(), ServerID: 100}); err != errNotRegistered {
+		t.Error("Test RewindDelayedReplica unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := RewindDelayedReplica(testEndpoint1, RewindOptions{Timestamp: time.Now(), ServerID: 100}); err != errNotSlave {
+		t.Error("Test RewindDelayedReplica testEndpoint1 error: should return errNotSlave")
+	}
+}
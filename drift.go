@@ -0,0 +1,240 @@
+package msops
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompareOptions describes which variables CompareVariables compares and how.
+type CompareOptions struct {
+	// Pattern is the pattern of "SHOW GLOBAL VARIABLES LIKE", "%" by default.
+	Pattern string
+
+	// Ignore lists the variables ignored besides the ones unique per instance, such as server_id.
+	Ignore []string
+
+	// Baseline is the desired state of the variables. If it's not nil, every instance is compared to it
+	// on the variables in it, instead of being compared to each other.
+	Baseline map[string]string
+}
+
+// VariableDiff represents a variable having different values.
+type VariableDiff struct {
+	Name string
+
+	// Expected is the value in the baseline, which is empty if the instances are compared to each other.
+	Expected string
+
+	// Values maps the endpoints to their values. The endpoints not having the variable are absent.
+	Values map[string]string
+}
+
+// VariableDriftReport is the result of CompareVariables.
+type VariableDriftReport struct {
+	// Diffs is sorted by the variable name.
+	Diffs []VariableDiff
+
+	// Errors records the endpoints whose variables can't be read, which are excluded from Diffs.
+	Errors map[string]error
+}
+
+// uniqueVariables are naturally different between instances and ignored by CompareVariables.
+var uniqueVariables = []string{
+	"server_id", "server_uuid", "hostname", "port", "report_host", "report_port",
+	"pid_file", "gtid_executed", "gtid_purged", "timestamp",
+}
+
+var (
+	errNoEndpoints   = errors.New("at least two endpoints or a baseline are required")
+	variableSizeExp  = regexp.MustCompile(`^(\d+)([KMGT])$`)
+	variableFloatExp = regexp.MustCompile(`^[-+]?(\d+\.\d*|\.\d+)$`)
+	variableUnitBits = map[string]uint{"K": 10, "M": 20, "G": 30, "T": 40}
+)
+
+// CompareVariables reads the global variables of the endpoints in parallel and reports the differences.
+//
+// The values are compared after normalization, so that "on", "ON", "TRUE" and "1" are equal for
+// boolean variables, and "128M" equals to "134217728".
+func CompareVariables(endpoints []string, opts CompareOptions) (VariableDriftReport, error) {
+	report := VariableDriftReport{Errors: make(map[string]error)}
+	if len(endpoints) == 0 || (len(endpoints) == 1 && opts.Baseline == nil) {
+		return report, errNoEndpoints
+	}
+	for _, endpoint := range endpoints {
+		if _, exists := getInstance(endpoint); !exists {
+			return report, errNotRegistered
+		}
+	}
+	pattern := opts.Pattern
+	if pattern == "" {
+		pattern = "%"
+	}
+
+	variables := make(map[string]map[string]string)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			values, err := GetGlobalVariables(endpoint, pattern)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				report.Errors[endpoint] = err
+			} else {
+				variables[endpoint] = values
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	ignored := make(map[string]bool)
+	for _, name := range append(append([]string(nil), uniqueVariables...), opts.Ignore...) {
+		ignored[strings.ToLower(name)] = true
+	}
+	names := make(map[string]bool)
+	if opts.Baseline != nil {
+		for name := range opts.Baseline {
+			names[normalizeVariableName(name)] = true
+		}
+	} else {
+		for _, values := range variables {
+			for name := range values {
+				names[name] = true
+			}
+		}
+	}
+	baseline := make(map[string]string)
+	for name, value := range opts.Baseline {
+		baseline[normalizeVariableName(name)] = value
+	}
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		if !ignored[name] {
+			sortedNames = append(sortedNames, name)
+		}
+	}
+	sort.Strings(sortedNames)
+	for _, name := range sortedNames {
+		diff := VariableDiff{Name: name, Expected: baseline[name], Values: make(map[string]string)}
+		var differs bool
+		var first string
+		var firstSet bool
+		for _, endpoint := range endpoints {
+			values, ok := variables[endpoint]
+			if !ok {
+				continue
+			}
+			value, exists := values[name]
+			if !exists {
+				differs = true
+				continue
+			}
+			diff.Values[endpoint] = value
+			if opts.Baseline != nil {
+				differs = differs || !variableValuesEqual(value, diff.Expected)
+			} else if !firstSet {
+				first, firstSet = value, true
+			} else {
+				differs = differs || !variableValuesEqual(value, first)
+			}
+		}
+		if differs {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+	return report, nil
+}
+
+// LoadVariableBaseline reads the desired state of variables from a file in the option file format, e.g.
+//
+//	[mysqld]
+//	binlog_format = ROW
+//	innodb-buffer-pool-size = 1G
+//
+// The section headers are ignored, and dashes in the names are replaced with underscores.
+func LoadVariableBaseline(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseVariableBaseline(file)
+}
+
+func parseVariableBaseline(r io.Reader) (map[string]string, error) {
+	baseline := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '[' {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		name := normalizeVariableName(parts[0])
+		value := "ON"
+		if len(parts) == 2 {
+			value = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		}
+		baseline[name] = value
+	}
+	return baseline, scanner.Err()
+}
+
+func normalizeVariableName(name string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(name)), "-", "_", -1)
+}
+
+// normalizeVariable converts the boolean values to "ON" or "OFF", the sizes with units to bytes,
+// and the decimals to their shortest form so that "2.000000" equals "2".
+func normalizeVariable(value string) string {
+	value = strings.TrimSpace(value)
+	switch strings.ToUpper(value) {
+	case "ON", "TRUE", "YES":
+		return "ON"
+	case "OFF", "FALSE", "NO":
+		return "OFF"
+	}
+	if matches := variableSizeExp.FindStringSubmatch(strings.ToUpper(value)); len(matches) == 3 {
+		if size, err := strconv.ParseUint(matches[1], 10, 64); err == nil {
+			return strconv.FormatUint(size<<variableUnitBits[matches[2]], 10)
+		}
+	}
+	if variableFloatExp.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return value
+}
+
+// variableValuesEqual compares the normalized values, treating "1" and "0" as "ON" and "OFF"
+// if the other is a boolean.
+func variableValuesEqual(a, b string) bool {
+	a, b = normalizeVariable(a), normalizeVariable(b)
+	if a == b {
+		return true
+	}
+	toBool := func(v string) string {
+		switch v {
+		case "1":
+			return "ON"
+		case "0":
+			return "OFF"
+		}
+		return v
+	}
+	if a == "ON" || a == "OFF" || b == "ON" || b == "OFF" {
+		return toBool(a) == toBool(b)
+	}
+	return false
+}
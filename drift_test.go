@@ -0,0 +1,80 @@
+package msops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVariableValuesEqual(t *testing.T) {
+	cases := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"ON", "on", true},
+		{"TRUE", "1", true},
+		{"OFF", "0", true},
+		{"ON", "0", false},
+		{"128M", "134217728", true},
+		{"1G", "1024m", true},
+		{"ROW", "MIXED", false},
+		{"1", "2", false},
+		{"2", "2.000000", true},
+		{"0.5", "0.500000", true},
+		{"2.5", "2.000000", false},
+		{"5.7.40", "5.7.4", false},
+	}
+	for _, c := range cases {
+		if variableValuesEqual(c.a, c.b) != c.equal {
+			t.Errorf("Test variableValuesEqual failed: %s and %s should be equal: %v", c.a, c.b, c.equal)
+		}
+	}
+}
+
+func TestParseVariableBaseline(t *testing.T) {
+	baseline, err := parseVariableBaseline(strings.NewReader(`
+# desired state
+[mysqld]
+binlog_format = ROW
+innodb-buffer-pool-size = "1G"
+skip-name-resolve
+`))
+	expected := map[string]string{"binlog_format": "ROW", "innodb_buffer_pool_size": "1G", "skip_name_resolve": "ON"}
+	if err != nil {
+		t.Errorf("Test parseVariableBaseline error: %s", err.Error())
+	} else if !reflect.DeepEqual(baseline, expected) {
+		t.Errorf("Test parseVariableBaseline failed: actual %v, expected %v", baseline, expected)
+	}
+}
+
+func TestCompareVariables(t *testing.T) {
+	if _, err := CompareVariables([]string{testEndpoint1}, CompareOptions{}); err != errNoEndpoints {
+		t.Error("Test CompareVariables one endpoint error: should return errNoEndpoints")
+	}
+	if _, err := CompareVariables([]string{testEndpoint1, unregisteredEndpoint}, CompareOptions{}); err != errNotRegistered {
+		t.Error("Test CompareVariables unregisteredEndpoint error: should return errNotRegistered")
+	}
+
+	endpoints := []string{testEndpoint1, testEndpoint2, badEndpoint}
+	report, err := CompareVariables(endpoints, CompareOptions{Pattern: "server%"})
+	if err != nil {
+		t.Fatalf("Test CompareVariables error: %s", err.Error())
+	}
+	if _, exists := report.Errors[badEndpoint]; !exists || len(report.Errors) != 1 {
+		t.Errorf("Test CompareVariables failed: actual errors %v, expected badEndpoint only", report.Errors)
+	}
+	for _, diff := range report.Diffs {
+		if diff.Name == "server_id" || diff.Name == "server_uuid" {
+			t.Errorf("Test CompareVariables failed: %s should be ignored", diff.Name)
+		}
+	}
+
+	// long_query_time is reported as 10.000000 and mustn't drift from 10.
+	baseline := map[string]string{"log-bin": "ON", "read_only": "OFF", "long_query_time": "10"}
+	if report, err = CompareVariables([]string{testEndpoint1}, CompareOptions{Baseline: baseline}); err != nil {
+		t.Fatalf("Test CompareVariables baseline error: %s", err.Error())
+	}
+	if len(report.Diffs) != 1 || report.Diffs[0].Name != "read_only" || report.Diffs[0].Expected != "OFF" {
+		t.Errorf("Test CompareVariables baseline failed: actual diffs %+v, expected read_only only", report.Diffs)
+	}
+}
@@ -0,0 +1,192 @@
+package msops
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReplicationFilters represents the replication filters of a slave.
+//
+// Tables are in the form "db.table", and wild tables are patterns such as "db%.tbl_%".
+type ReplicationFilters struct {
+	DoDB            []string
+	IgnoreDB        []string
+	DoTable         []string
+	IgnoreTable     []string
+	WildDoTable     []string
+	WildIgnoreTable []string
+}
+
+var (
+	errFilterUnsupported  = errors.New("CHANGE REPLICATION FILTER is supported since MySQL 5.7.3")
+	errChannelUnsupported = errors.New("replication channel is supported since MySQL 8.0.2 for replication filters")
+	errFilterTableInvalid = errors.New("the filter table should be in the form db.table")
+)
+
+// GetReplicationFilters reads the replication filters from the slave status of the endpoint.
+func GetReplicationFilters(endpoint string) (ReplicationFilters, error) {
+	slaveStatus, err := GetSlaveStatus(endpoint)
+	if err != nil {
+		return ReplicationFilters{}, err
+	}
+	return parseReplicationFilters(slaveStatus), nil
+}
+
+// parseReplicationFilters parses the comma-separated filter fields of the slave status.
+func parseReplicationFilters(slaveStatus SlaveStatus) ReplicationFilters {
+	return ReplicationFilters{
+		DoDB:            splitFilter(slaveStatus.ReplicateDoDB),
+		IgnoreDB:        splitFilter(slaveStatus.ReplicateIgnoreDB),
+		DoTable:         splitFilter(slaveStatus.ReplicateDoTable),
+		IgnoreTable:     splitFilter(slaveStatus.ReplicateIgnoreTable),
+		WildDoTable:     splitFilter(slaveStatus.ReplicateWildDoTable),
+		WildIgnoreTable: splitFilter(slaveStatus.ReplicateWildIgnoreTable),
+	}
+}
+
+func splitFilter(field string) []string {
+	var values []string
+	for _, value := range strings.Split(field, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ChangeReplicationFilter replaces all the replication filters of the slave with filters,
+// i.e. the empty fields of filters clear the corresponding filters.
+// The SQL thread is stopped during the change and restarted if it was running.
+func ChangeReplicationFilter(slaveEndpoint string, filters ReplicationFilters) error {
+	return ChangeReplicationFilterForChannel(slaveEndpoint, "", filters)
+}
+
+// ChangeReplicationFilterForChannel is the same as ChangeReplicationFilter,
+// but changes the filters of the replication channel, which requires MySQL 8.0.2.
+// The filters of all the channels are changed if channel is empty.
+func ChangeReplicationFilterForChannel(slaveEndpoint, channel string, filters ReplicationFilters) error {
+	var version serverVersion
+	var slaveStatus SlaveStatus
+	var query string
+	var err error
+	if query, err = filters.statement(); err != nil {
+		return err
+	}
+	if version, err = getVersion(slaveEndpoint); err != nil {
+		return err
+	}
+	if !version.atLeast(5, 7, 3) {
+		return errFilterUnsupported
+	}
+	var forChannel string
+	if channel != "" {
+		if !version.atLeast(8, 0, 2) {
+			return errChannelUnsupported
+		}
+		forChannel = " FOR CHANNEL " + quoteString(channel)
+	}
+	if slaveStatus, err = GetSlaveStatus(slaveEndpoint); err != nil {
+		return err
+	}
+	running := slaveStatus.SlaveSQLRunning == "Yes"
+	if running {
+		if err = execute(slaveEndpoint, "STOP SLAVE SQL_THREAD"+forChannel); err != nil {
+			return err
+		}
+	}
+	if err = execute(slaveEndpoint, query+forChannel); err != nil {
+		return err
+	}
+	if running {
+		return execute(slaveEndpoint, "START SLAVE SQL_THREAD"+forChannel)
+	}
+	return nil
+}
+
+// statement builds "CHANGE REPLICATION FILTER" with the database and table names quoted.
+func (filters ReplicationFilters) statement() (string, error) {
+	var tables [2][]string
+	for i, values := range [][]string{filters.DoTable, filters.IgnoreTable} {
+		for _, table := range values {
+			parts := strings.SplitN(table, ".", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return "", errFilterTableInvalid
+			}
+			tables[i] = append(tables[i], quoteIdentifier(parts[0])+"."+quoteIdentifier(parts[1]))
+		}
+	}
+	clauses := []string{
+		"REPLICATE_DO_DB = (" + joinQuoted(filters.DoDB, quoteIdentifier) + ")",
+		"REPLICATE_IGNORE_DB = (" + joinQuoted(filters.IgnoreDB, quoteIdentifier) + ")",
+		"REPLICATE_DO_TABLE = (" + strings.Join(tables[0], ", ") + ")",
+		"REPLICATE_IGNORE_TABLE = (" + strings.Join(tables[1], ", ") + ")",
+		"REPLICATE_WILD_DO_TABLE = (" + joinQuoted(filters.WildDoTable, quoteString) + ")",
+		"REPLICATE_WILD_IGNORE_TABLE = (" + joinQuoted(filters.WildIgnoreTable, quoteString) + ")",
+	}
+	return "CHANGE REPLICATION FILTER " + strings.Join(clauses, ", "), nil
+}
+
+func joinQuoted(values []string, quote func(string) string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, quote(value))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// differences returns the names of the filters which differ from other, ignoring the order of values.
+func (filters ReplicationFilters) differences(other ReplicationFilters) []string {
+	var names []string
+	a, b := reflect.ValueOf(filters), reflect.ValueOf(other)
+	for i := 0; i < a.NumField(); i++ {
+		if !sameStrings(a.Field(i).Interface().([]string), b.Field(i).Interface().([]string)) {
+			names = append(names, a.Type().Field(i).Name)
+		}
+	}
+	return names
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x, y := append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	return reflect.DeepEqual(x, y)
+}
+
+// CheckReplicationFilters compares the replication filters of the slaves replicating from the same master,
+// adding a warning for each pair of slaves whose filters differ, since they may have different data.
+// The endpoints which are not slaves are reported as warnings as well.
+func CheckReplicationFilters(slaveEndpoints ...string) (ReplicationCheck, error) {
+	var check ReplicationCheck
+	firstSlaves := make(map[string]string)
+	firstFilters := make(map[string]ReplicationFilters)
+	for _, endpoint := range slaveEndpoints {
+		slaveStatus, err := GetSlaveStatus(endpoint)
+		if err != nil {
+			return check, err
+		}
+		if reflect.DeepEqual(emptySlaveStatus, slaveStatus) {
+			check.addWarning("%s is not a slave", endpoint)
+			continue
+		}
+		master := net.JoinHostPort(slaveStatus.MasterHost, strconv.Itoa(slaveStatus.MasterPort))
+		filters := parseReplicationFilters(slaveStatus)
+		first, exists := firstSlaves[master]
+		if !exists {
+			firstSlaves[master], firstFilters[master] = endpoint, filters
+			continue
+		}
+		if names := firstFilters[master].differences(filters); len(names) > 0 {
+			check.addWarning("replication filters of slaves of %s differ between %s and %s: %s",
+				master, first, endpoint, strings.Join(names, ", "))
+		}
+	}
+	return check, nil
+}
@@ -0,0 +1,82 @@
+package msops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReplicationFilters(t *testing.T) {
+	filters := parseReplicationFilters(SlaveStatus{
+		ReplicateDoDB:        "db1, db2",
+		ReplicateIgnoreTable: "db1.tbl_log",
+		ReplicateWildDoTable: "db%.tbl_%",
+	})
+	expected := ReplicationFilters{
+		DoDB:        []string{"db1", "db2"},
+		IgnoreTable: []string{"db1.tbl_log"},
+		WildDoTable: []string{"db%.tbl_%"},
+	}
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("Test parseReplicationFilters failed: actual %+v, expected %+v", filters, expected)
+	}
+
+	other := ReplicationFilters{DoDB: []string{"db2", "db1"}, IgnoreTable: []string{"db1.tbl_log"}}
+	if names := filters.differences(other); !reflect.DeepEqual(names, []string{"WildDoTable"}) {
+		t.Errorf("Test ReplicationFilters differences failed: actual %v, expected [WildDoTable]", names)
+	}
+}
+
+func TestReplicationFiltersStatement(t *testing.T) {
+	filters := ReplicationFilters{
+		DoDB:        []string{"db1", "db`2"},
+		IgnoreTable: []string{"db1.tbl_log"},
+		WildDoTable: []string{"db%.tbl_%"},
+	}
+	expected := "CHANGE REPLICATION FILTER REPLICATE_DO_DB = (`db1`, `db``2`), REPLICATE_IGNORE_DB = (), " +
+		"REPLICATE_DO_TABLE = (), REPLICATE_IGNORE_TABLE = (`db1`.`tbl_log`), " +
+		"REPLICATE_WILD_DO_TABLE = ('db%.tbl_%'), REPLICATE_WILD_IGNORE_TABLE = ()"
+	if query, err := filters.statement(); err != nil {
+		t.Errorf("Test ReplicationFilters statement error: %s", err.Error())
+	} else if query != expected {
+		t.Errorf("Test ReplicationFilters statement failed: actual %s, expected %s", query, expected)
+	}
+	if _, err := (ReplicationFilters{DoTable: []string{"tbl"}}).statement(); err != errFilterTableInvalid {
+		t.Error("Test ReplicationFilters statement with invalid table error: should return errFilterTableInvalid")
+	}
+}
+
+func TestChangeReplicationFilter(t *testing.T) {
+	if ChangeReplicationFilter(unregisteredEndpoint, ReplicationFilters{}) != errNotRegistered {
+		t.Error("Test ChangeReplicationFilter unregisteredEndpoint error: should return errNotRegistered")
+	}
+	version, err := getVersion(testEndpoint2)
+	if err != nil {
+		t.Fatalf("Test getVersion error: %s", err.Error())
+	}
+	if !version.atLeast(5, 7, 3) {
+		if ChangeReplicationFilter(testEndpoint2, ReplicationFilters{}) != errFilterUnsupported {
+			t.Error("Test ChangeReplicationFilter before 5.7.3 error: should return errFilterUnsupported")
+		}
+		return
+	}
+	filters := ReplicationFilters{IgnoreDB: []string{"db_ignored"}}
+	if err = ChangeReplicationFilter(testEndpoint2, filters); err != nil {
+		t.Errorf("Test ChangeReplicationFilter error: %s", err.Error())
+	} else if actual, err := GetReplicationFilters(testEndpoint2); err != nil {
+		t.Errorf("Test GetReplicationFilters error: %s", err.Error())
+	} else if len(actual.differences(filters)) > 0 {
+		t.Errorf("Test ChangeReplicationFilter failed: actual %+v, expected %+v", actual, filters)
+	}
+	ChangeReplicationFilter(testEndpoint2, ReplicationFilters{})
+}
+
+func TestCheckReplicationFilters(t *testing.T) {
+	if _, err := CheckReplicationFilters(testEndpoint2, unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test CheckReplicationFilters unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if check, err := CheckReplicationFilters(testEndpoint1); err != nil {
+		t.Errorf("Test CheckReplicationFilters error: %s", err.Error())
+	} else if len(check.Warnings) != 1 {
+		t.Errorf("Test CheckReplicationFilters failed: actual warnings %v, expected testEndpoint1 is not a slave", check.Warnings)
+	}
+}
@@ -0,0 +1,261 @@
+package msops
+
+import (
+	"errors"
+)
+
+// GroupMember represents one row data of performance_schema.replication_group_members.
+// Based on 8.0 MySQL Community Server.
+//
+// MemberRole is computed from 'group_replication_primary_member' before MySQL 8.0.2,
+// where every ONLINE member is PRIMARY in multi-primary mode.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/8.0/en/performance-schema-replication-group-members-table.html
+type GroupMember struct {
+	ChannelName   string
+	MemberID      string
+	MemberHost    string
+	MemberPort    int
+	MemberState   string
+	MemberRole    string
+	MemberVersion string
+}
+
+// GroupMemberStats represents one row data of performance_schema.replication_group_member_stats.
+// Based on 8.0 MySQL Community Server, the fields not existing in 5.7 are left empty.
+// Note that only the stats of the local member are provided before MySQL 8.0.2.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/8.0/en/performance-schema-replication-group-member-stats-table.html
+type GroupMemberStats struct {
+	ChannelName                           string
+	ViewID                                string
+	MemberID                              string
+	CountTransactionsInQueue              int
+	CountTransactionsChecked              int
+	CountConflictsDetected                int
+	CountTransactionsRowsValidating       int
+	TransactionsCommittedAllMembers       string
+	LastConflictFreeTransaction           string
+	CountTransactionsRemoteInApplierQueue int
+	CountTransactionsRemoteApplied        int
+	CountTransactionsLocalProposed        int
+	CountTransactionsLocalRollback        int
+}
+
+// GroupMemberStatus represents the group replication status of one instance.
+//
+// The judgement is according to 'MEMBER_STATE' of the local member in performance_schema.replication_group_members.
+type GroupMemberStatus int
+
+const (
+	// GroupMemberOnline implies that the member is ONLINE and fully synchronized with the group.
+	GroupMemberOnline GroupMemberStatus = iota
+
+	// GroupMemberRecovering implies that the member is RECOVERING the transactions from a donor.
+	GroupMemberRecovering
+
+	// GroupMemberOffline implies that the group replication plugin is installed but not running.
+	GroupMemberOffline
+
+	// GroupMemberError implies that the member is in ERROR state and has left the group.
+	GroupMemberError
+
+	// GroupMemberUnreachable implies that the member is UNREACHABLE to the others.
+	GroupMemberUnreachable
+
+	// GroupMemberNone implies that the instance is not a group member.
+	GroupMemberNone
+
+	// GroupMemberUnknown implies that we can't connect to the instance.
+	GroupMemberUnknown
+)
+
+// Member roles of the group.
+const (
+	GroupMemberPrimary   = "PRIMARY"
+	GroupMemberSecondary = "SECONDARY"
+)
+
+var (
+	errNoGroupPrimary        = errors.New("there's no primary in the group")
+	errMultiPrimary          = errors.New("the group is in multi-primary mode")
+	errSetPrimaryUnsupported = errors.New("group_replication_set_as_primary is supported since MySQL 8.0.13")
+)
+
+// GetGroupMembers reads performance_schema.replication_group_members of the endpoint.
+func GetGroupMembers(endpoint string) ([]GroupMember, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.replication_group_members"); err != nil {
+		return nil, err
+	}
+	members := make([]GroupMember, 0, len(dataSet))
+	var roleExists bool
+	for _, row := range dataSet {
+		var role string
+		role, roleExists = row["MEMBER_ROLE"]
+		members = append(members, GroupMember{
+			ChannelName:   row["CHANNEL_NAME"],
+			MemberID:      row["MEMBER_ID"],
+			MemberHost:    row["MEMBER_HOST"],
+			MemberPort:    getInt(row["MEMBER_PORT"]),
+			MemberState:   row["MEMBER_STATE"],
+			MemberRole:    role,
+			MemberVersion: row["MEMBER_VERSION"],
+		})
+	}
+	if len(members) == 0 || roleExists {
+		return members, nil
+	}
+
+	var status map[string]string
+	if status, err = GetGlobalStatus(endpoint, "group_replication_primary_member"); err != nil {
+		return nil, err
+	}
+	fillGroupMemberRoles(members, status["group_replication_primary_member"])
+	return members, nil
+}
+
+// fillGroupMemberRoles sets the roles of the ONLINE members according to the uuid of the primary,
+// which is empty in multi-primary mode.
+func fillGroupMemberRoles(members []GroupMember, primary string) {
+	for i := range members {
+		if members[i].MemberState != "ONLINE" {
+			continue
+		}
+		if primary == "" || members[i].MemberID == primary {
+			members[i].MemberRole = GroupMemberPrimary
+		} else {
+			members[i].MemberRole = GroupMemberSecondary
+		}
+	}
+}
+
+// GetGroupMemberStats reads performance_schema.replication_group_member_stats of the endpoint.
+func GetGroupMemberStats(endpoint string) ([]GroupMemberStats, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.replication_group_member_stats"); err != nil {
+		return nil, err
+	}
+	stats := make([]GroupMemberStats, 0, len(dataSet))
+	for _, row := range dataSet {
+		stats = append(stats, GroupMemberStats{
+			ChannelName:                           row["CHANNEL_NAME"],
+			ViewID:                                row["VIEW_ID"],
+			MemberID:                              row["MEMBER_ID"],
+			CountTransactionsInQueue:              getInt(row["COUNT_TRANSACTIONS_IN_QUEUE"]),
+			CountTransactionsChecked:              getInt(row["COUNT_TRANSACTIONS_CHECKED"]),
+			CountConflictsDetected:                getInt(row["COUNT_CONFLICTS_DETECTED"]),
+			CountTransactionsRowsValidating:       getInt(row["COUNT_TRANSACTIONS_ROWS_VALIDATING"]),
+			TransactionsCommittedAllMembers:       row["TRANSACTIONS_COMMITTED_ALL_MEMBERS"],
+			LastConflictFreeTransaction:           row["LAST_CONFLICT_FREE_TRANSACTION"],
+			CountTransactionsRemoteInApplierQueue: getInt(row["COUNT_TRANSACTIONS_REMOTE_IN_APPLIER_QUEUE"]),
+			CountTransactionsRemoteApplied:        getInt(row["COUNT_TRANSACTIONS_REMOTE_APPLIED"]),
+			CountTransactionsLocalProposed:        getInt(row["COUNT_TRANSACTIONS_LOCAL_PROPOSED"]),
+			CountTransactionsLocalRollback:        getInt(row["COUNT_TRANSACTIONS_LOCAL_ROLLBACK"]),
+		})
+	}
+	return stats, nil
+}
+
+// GetGroupPrimary returns the primary of the group seen by the endpoint.
+// An error is returned if the group is in multi-primary mode or there's no primary.
+func GetGroupPrimary(endpoint string) (GroupMember, error) {
+	var members []GroupMember
+	var err error
+	if members, err = GetGroupMembers(endpoint); err != nil {
+		return GroupMember{}, err
+	}
+	var primaries []GroupMember
+	for _, member := range members {
+		if member.MemberRole == GroupMemberPrimary {
+			primaries = append(primaries, member)
+		}
+	}
+	switch len(primaries) {
+	case 0:
+		return GroupMember{}, errNoGroupPrimary
+	case 1:
+		return primaries[0], nil
+	}
+	return GroupMember{}, errMultiPrimary
+}
+
+// CheckGroupMember checks the group replication status of the instance with the endpoint.
+// Note that if the instance is not registered or reading the members failed, GroupMemberUnknown is returned.
+func CheckGroupMember(endpoint string) GroupMemberStatus {
+	if CheckInstance(endpoint) != InstanceOK {
+		return GroupMemberUnknown
+	}
+	var version serverVersion
+	var variables map[string]string
+	var members []GroupMember
+	var err error
+	if version, err = getVersion(endpoint); err != nil {
+		return GroupMemberUnknown
+	}
+	if !version.atLeast(5, 7, 17) {
+		return GroupMemberNone
+	}
+	if variables, err = GetGlobalVariables(endpoint, "server_uuid"); err != nil {
+		return GroupMemberUnknown
+	}
+	if members, err = GetGroupMembers(endpoint); err != nil {
+		return GroupMemberUnknown
+	}
+	for _, member := range members {
+		// The local member has an empty MEMBER_ID if the plugin is installed but never started.
+		if member.MemberID != variables["server_uuid"] && member.MemberID != "" {
+			continue
+		}
+		switch member.MemberState {
+		case "ONLINE":
+			return GroupMemberOnline
+		case "RECOVERING":
+			return GroupMemberRecovering
+		case "ERROR":
+			return GroupMemberError
+		case "UNREACHABLE":
+			return GroupMemberUnreachable
+		default:
+			return GroupMemberOffline
+		}
+	}
+	return GroupMemberNone
+}
+
+// StartGroupReplication executes "START GROUP_REPLICATION" at the endpoint.
+// If bootstrap is true, group_replication_bootstrap_group is turned on during starting to bootstrap a new group.
+func StartGroupReplication(endpoint string, bootstrap bool) error {
+	if !bootstrap {
+		return execute(endpoint, "START GROUP_REPLICATION")
+	}
+	if err := SetGlobalVariable(endpoint, "group_replication_bootstrap_group", "ON"); err != nil {
+		return err
+	}
+	err := execute(endpoint, "START GROUP_REPLICATION")
+	if e := SetGlobalVariable(endpoint, "group_replication_bootstrap_group", "OFF"); err == nil {
+		err = e
+	}
+	return err
+}
+
+// StopGroupReplication executes "STOP GROUP_REPLICATION" at the endpoint.
+func StopGroupReplication(endpoint string) error {
+	return execute(endpoint, "STOP GROUP_REPLICATION")
+}
+
+// SetGroupPrimary makes the member with memberUUID as the new primary of the single-primary group,
+// by executing group_replication_set_as_primary() at the endpoint.
+func SetGroupPrimary(endpoint, memberUUID string) error {
+	var version serverVersion
+	var err error
+	if version, err = getVersion(endpoint); err != nil {
+		return err
+	}
+	if !version.atLeast(8, 0, 13) {
+		return errSetPrimaryUnsupported
+	}
+	return execute(endpoint, "SELECT group_replication_set_as_primary(?)", memberUUID)
+}
@@ -0,0 +1,62 @@
+package msops
+
+import (
+	"testing"
+)
+
+func TestFillGroupMemberRoles(t *testing.T) {
+	members := []GroupMember{
+		{MemberID: "uuid-1", MemberState: "ONLINE"},
+		{MemberID: "uuid-2", MemberState: "ONLINE"},
+		{MemberID: "uuid-3", MemberState: "RECOVERING"},
+	}
+	fillGroupMemberRoles(members, "uuid-2")
+	if members[0].MemberRole != GroupMemberSecondary || members[1].MemberRole != GroupMemberPrimary || members[2].MemberRole != "" {
+		t.Errorf("Test fillGroupMemberRoles single-primary failed: actual members %+v", members)
+	}
+	fillGroupMemberRoles(members, "")
+	if members[0].MemberRole != GroupMemberPrimary || members[1].MemberRole != GroupMemberPrimary {
+		t.Errorf("Test fillGroupMemberRoles multi-primary failed: actual members %+v", members)
+	}
+}
+
+func TestCheckGroupMember(t *testing.T) {
+	if status := CheckGroupMember(unregisteredEndpoint); status != GroupMemberUnknown {
+		t.Errorf("Test CheckGroupMember unregisteredEndpoint failed: actual %d, expected GroupMemberUnknown", status)
+	}
+	if status := CheckGroupMember(badEndpoint); status != GroupMemberUnknown {
+		t.Errorf("Test CheckGroupMember badEndpoint failed: actual %d, expected GroupMemberUnknown", status)
+	}
+	if status := CheckGroupMember(testEndpoint1); status != GroupMemberNone {
+		t.Errorf("Test CheckGroupMember testEndpoint1 failed: actual %d, expected GroupMemberNone", status)
+	}
+}
+
+func TestGroupReplication(t *testing.T) {
+	if _, err := GetGroupMembers(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test GetGroupMembers unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := GetGroupMemberStats(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test GetGroupMemberStats unregisteredEndpoint error: should return errNotRegistered")
+	}
+	version, err := getVersion(testEndpoint1)
+	if err != nil {
+		t.Fatalf("Test getVersion error: %s", err.Error())
+	}
+	if !version.atLeast(8, 0, 13) {
+		if err = SetGroupPrimary(testEndpoint1, "uuid"); err != errSetPrimaryUnsupported {
+			t.Error("Test SetGroupPrimary before 8.0.13 error: should return errSetPrimaryUnsupported")
+		}
+	}
+	if !version.atLeast(5, 7, 17) {
+		t.Skip("Group replication is not supported before MySQL 5.7.17")
+	}
+	if members, err := GetGroupMembers(testEndpoint1); err != nil {
+		t.Errorf("Test GetGroupMembers error: %s", err.Error())
+	} else if _, err = GetGroupPrimary(testEndpoint1); len(members) == 0 && err != errNoGroupPrimary {
+		t.Error("Test GetGroupPrimary without members error: should return errNoGroupPrimary")
+	}
+	if _, err := GetGroupMemberStats(testEndpoint1); err != nil {
+		t.Errorf("Test GetGroupMemberStats error: %s", err.Error())
+	}
+}
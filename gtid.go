@@ -0,0 +1,54 @@
+package msops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gtidInterval is a closed interval of transaction numbers.
+type gtidInterval struct {
+	start int64
+	end   int64
+}
+
+// gtidSet maps the lowercase source UUID to its intervals of transaction numbers.
+type gtidSet map[string][]gtidInterval
+
+// parseGtidSet parses a GTID set such as "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,\n3e11fa47-...:1".
+func parseGtidSet(s string) (gtidSet, error) {
+	set := make(gtidSet)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		uuid := strings.ToLower(fields[0])
+		for _, field := range fields[1:] {
+			bounds := strings.SplitN(field, "-", 2)
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GTID interval %s in %s", field, part)
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid GTID interval %s in %s", field, part)
+				}
+			}
+			set[uuid] = append(set[uuid], gtidInterval{start, end})
+		}
+	}
+	return set, nil
+}
+
+// contains reports whether the transaction uuid:gno is in the set.
+func (set gtidSet) contains(uuid string, gno int64) bool {
+	for _, interval := range set[strings.ToLower(uuid)] {
+		if gno >= interval.start && gno <= interval.end {
+			return true
+		}
+	}
+	return false
+}
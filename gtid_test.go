@@ -0,0 +1,24 @@
+package msops
+
+import "testing"
+
+func TestGtidSet(t *testing.T) {
+	uuid := "3E11FA47-71CA-11E1-9E33-C80AA9429562"
+	retrieved, err := parseGtidSet(uuid + ":1-10,\n8a94f357-aab4-11df-86ab-c80aa9429562:1-3")
+	if err != nil {
+		t.Fatalf("Test parseGtidSet error: %s", err.Error())
+	}
+	executed, err := parseGtidSet(uuid + ":1-5:7")
+	if err != nil {
+		t.Fatalf("Test parseGtidSet error: %s", err.Error())
+	}
+	if !executed.contains(uuid, 7) || executed.contains(uuid, 6) {
+		t.Error("Test gtidSet contains failed")
+	}
+	if len(retrieved) != 2 || len(retrieved["3e11fa47-71ca-11e1-9e33-c80aa9429562"]) != 1 {
+		t.Errorf("Test parseGtidSet failed: unexpected set %v", retrieved)
+	}
+	if _, err = parseGtidSet(uuid + ":a-b"); err == nil {
+		t.Error("Test parseGtidSet with invalid interval error: should return error")
+	}
+}
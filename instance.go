@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Instance records the connect information.
@@ -66,6 +67,10 @@ const (
 
 	// ReplicationUnknown implies that we can't connect to the slave instance.
 	ReplicationUnknown
+
+	// ReplicationSemiSyncDegraded implies that the replication is running, but the semi-sync master
+	// has fallen back to asynchronous replication, or the slave has semi-sync enabled but not active.
+	ReplicationSemiSyncDegraded
 )
 
 const (
@@ -82,12 +87,14 @@ const (
 const driverName = "mysql"
 
 var (
-	connectionPool      = make(map[string]*Instance)
-	errNotRegistered    = errors.New("the instance is not registered")
-	errKeyInvalid       = errors.New("the key is not valid")
-	emptySlaveStatus    = SlaveStatus{}
-	innodbSemaphoresExp = regexp.MustCompile(`^Mutex spin waits\s+(\d+),\s+rounds\s+(\d+),\s+OS waits\s+(\d+)`)
-	globalKeyExp        = regexp.MustCompile(`^[_0-9a-zA-Z][_0-9a-zA-Z]*`)
+	connectionPool       = make(map[string]*Instance)
+	poolMutex            sync.RWMutex
+	errNotRegistered     = errors.New("the instance is not registered")
+	errKeyInvalid        = errors.New("the key is not valid")
+	emptySlaveStatus     = SlaveStatus{}
+	innodbSemaphoresExp  = regexp.MustCompile(`^Mutex spin waits\s+(\d+),\s+rounds\s+(\d+),\s+OS waits\s+(\d+)`)
+	innodbHistoryListExp = regexp.MustCompile(`^History list length\s+(\d+)`)
+	globalKeyExp         = regexp.MustCompile(`^[_0-9a-zA-Z][_0-9a-zA-Z]*$`)
 )
 
 // Register registers the instance of endpoint with opening the connection with user 'dbaUser', password 'dbaPassword'.
@@ -104,19 +111,16 @@ var (
 //
 // If the final connection string generated is invalid, an error will be returned.
 func Register(endpoint, dbaUser, dbaPassword, replUser, replPassword string, params map[string]string) error {
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
 	if _, exist := connectionPool[endpoint]; !exist {
 		if params == nil {
 			params = make(map[string]string)
 		}
 		params["interpolateParams"] = "true"
-		paramSlice := make([]string, 0, len(params))
-		for key, value := range params {
-			paramSlice = append(paramSlice, fmt.Sprintf("%s=%s", key, value))
-		}
-		connStr := fmt.Sprintf("%s:%s@tcp(%s)/?%s", dbaUser, dbaPassword, endpoint, strings.Join(paramSlice, "&"))
 		var conn *sql.DB
 		var err error
-		if conn, err = sql.Open(driverName, connStr); err != nil {
+		if conn, err = sql.Open(driverName, connectionString(endpoint, dbaUser, dbaPassword, params)); err != nil {
 			return err
 		}
 		inst := &Instance{
@@ -134,15 +138,45 @@ func Register(endpoint, dbaUser, dbaPassword, replUser, replPassword string, par
 
 // Unregister deletes the information from msops's connection pool and closes the connections to endpoint.
 func Unregister(endpoint string) {
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
 	if inst, exist := connectionPool[endpoint]; exist {
 		inst.connection.Close()
 	}
 	delete(connectionPool, endpoint)
 }
 
+// getInstance returns the registered instance of endpoint. It's safe for concurrent use with Register and Unregister.
+func getInstance(endpoint string) (*Instance, bool) {
+	poolMutex.RLock()
+	defer poolMutex.RUnlock()
+	inst, exists := connectionPool[endpoint]
+	return inst, exists
+}
+
+// registeredEndpoints returns the endpoints of all the registered instances.
+func registeredEndpoints() []string {
+	poolMutex.RLock()
+	defer poolMutex.RUnlock()
+	endpoints := make([]string, 0, len(connectionPool))
+	for endpoint := range connectionPool {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// connectionString generates the go-mysql-driver connection string.
+func connectionString(endpoint, user, password string, params map[string]string) string {
+	paramSlice := make([]string, 0, len(params))
+	for key, value := range params {
+		paramSlice = append(paramSlice, fmt.Sprintf("%s=%s", key, value))
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s)/?%s", user, password, endpoint, strings.Join(paramSlice, "&"))
+}
+
 // CheckInstance checks the status of a instance with the endpoint.
 func CheckInstance(endpoint string) InstanceStatus {
-	if inst, exist := connectionPool[endpoint]; exist {
+	if inst, exist := getInstance(endpoint); exist {
 		if inst.connection.Ping() == nil {
 			return InstanceOK
 		}
@@ -151,7 +185,8 @@ func CheckInstance(endpoint string) InstanceStatus {
 	return InstanceUnregistered
 }
 
-// CheckReplication checks the replicaton status between slaveEndpoint and masterEndpoint.
+// CheckReplication checks the replicaton status between slaveEndpoint and masterEndpoint,
+// including the semi-synchronous replication if it's enabled on the master.
 // Note that if one of slave or master is not registered,
 // or getting MasterStatus and SlaveStatus failed, ReplicationUnknown is returned.
 func CheckReplication(slaveEndpoint, masterEndpoint string) ReplicationStatus {
@@ -161,6 +196,7 @@ func CheckReplication(slaveEndpoint, masterEndpoint string) ReplicationStatus {
 	}
 	var masterStatus MasterStatus
 	var slaveStatus SlaveStatus
+	var masterSemiSync, slaveSemiSync SemiSyncStatus
 	var err error
 	if masterStatus, err = GetMasterStatus(masterEndpoint); err != nil {
 		return ReplicationUnknown
@@ -180,6 +216,16 @@ func CheckReplication(slaveEndpoint, masterEndpoint string) ReplicationStatus {
 	if slaveStatus.SlaveSQLRunning == "No" && slaveStatus.SlaveIORunning == "No" {
 		return ReplicationPausing
 	}
+	if masterSemiSync, err = GetSemiSyncStatus(masterEndpoint); err != nil {
+		return ReplicationUnknown
+	}
+	if slaveSemiSync, err = GetSemiSyncStatus(slaveEndpoint); err != nil {
+		return ReplicationUnknown
+	}
+	if masterSemiSync.Master.health() == SemiSyncFallback ||
+		masterSemiSync.Master.Enabled && slaveSemiSync.Slave.Enabled && !slaveSemiSync.Slave.Active {
+		return ReplicationSemiSyncDegraded
+	}
 	if slaveStatus.MasterLogFile != masterStatus.File ||
 		slaveStatus.ExecMasterLogPos != masterStatus.Position {
 		return ReplicationSyning
@@ -1,6 +1,9 @@
 package msops
 
-import "testing"
+import (
+	"sync"
+	"testing"
+)
 
 func TestRegisterAndUnRegister(t *testing.T) {
 	if err := Register(testEndpoint1, testDBAUser, testDBAPass, testReplUser, testReplPass, testParams); err != nil {
@@ -37,3 +40,29 @@ func TestCheckInstanceStatus(t *testing.T) {
 		t.Errorf("UnregisteredEndpoint instance status error: actual %d, expect %d", inst, InstanceUnregistered)
 	}
 }
+
+// TestRegisterConcurrently reads the connection pool while registering and unregistering,
+// where the data race is reported by "go test -race".
+func TestRegisterConcurrently(t *testing.T) {
+	endpoint := "127.0.0.1:3399"
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := Register(endpoint, testDBAUser, testDBAPass, testReplUser, testReplPass, nil); err != nil {
+				t.Errorf("Register concurrently error: %s", err.Error())
+			}
+			Unregister(endpoint)
+		}()
+		go func() {
+			defer wg.Done()
+			getInstance(endpoint)
+			registeredEndpoints()
+		}()
+	}
+	wg.Wait()
+	if _, exists := getInstance(endpoint); exists {
+		t.Error("Register concurrently error: endpoint should be unregistered")
+	}
+}
@@ -0,0 +1,223 @@
+package msops
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountInfo describes an account in mysql.user and its privileges.
+type AccountInfo struct {
+	Account
+
+	// Plugin is the authentication plugin, e.g. "mysql_native_password".
+	Plugin string
+
+	PasswordExpired bool
+
+	// PasswordLastChanged and PasswordLifetime are available since MySQL 5.7.6.
+	// PasswordLifetime is the days before the password expires, where 0 means never
+	// and -1 means default_password_lifetime applies.
+	PasswordLastChanged string
+	PasswordLifetime    int
+
+	// Locked is available since MySQL 5.7.6.
+	Locked bool
+
+	Grants []AccountGrant
+
+	// Roles lists the roles granted to the account on MySQL 8.0, e.g. "`app_read`@`%`".
+	Roles []string
+
+	// Error is the error of reading the grants of the account, whose Grants and Roles are unknown if it's not nil.
+	Error error
+}
+
+// AccountGrant represents a line of "SHOW GRANTS" granting privileges on a level.
+type AccountGrant struct {
+	// Privileges are as shown by "SHOW GRANTS", e.g. "SELECT", "ALL PRIVILEGES" or "SELECT (`id`, `name`)".
+	Privileges []string
+
+	// Level is the privilege level, e.g. "*.*", "`data_test`.*" or "PROCEDURE `db`.`proc`".
+	Level string
+
+	GrantOption bool
+}
+
+// AccountDiff represents an account present on some of the endpoints but not the others.
+type AccountDiff struct {
+	Account
+	Present []string
+	Missing []string
+}
+
+// AccountDiffReport is the result of CompareAccounts.
+type AccountDiffReport struct {
+	// Diffs is sorted by the user and host.
+	Diffs []AccountDiff
+
+	// Errors records the endpoints whose accounts can't be read, which are excluded from Diffs.
+	Errors map[string]error
+}
+
+var errTooFewEndpoints = errors.New("at least two endpoints are required")
+
+var grantLineExp = regexp.MustCompile(`^GRANT (.+?) ON ((?:(?:FUNCTION|PROCEDURE) )?\S+) TO (.+)$`)
+
+// ListAccounts reads the accounts from mysql.user of the endpoint and parses their grants, sorted by the user and host.
+// The anonymous accounts are included. If the grants of an account can't be read, the error is recorded in
+// its Error without failing the others.
+func ListAccounts(endpoint string) ([]AccountInfo, error) {
+	version, err := getVersion(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT User, Host, plugin, password_expired FROM mysql.user ORDER BY User, Host"
+	if version.atLeast(5, 7, 6) {
+		query = "SELECT User, Host, plugin, password_expired, password_last_changed, password_lifetime, account_locked " +
+			"FROM mysql.user ORDER BY User, Host"
+	}
+	dataSet, err := readDataSet(endpoint, query)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]AccountInfo, 0, len(dataSet))
+	for _, row := range dataSet {
+		account := AccountInfo{
+			Account:             Account{User: row["User"], Host: row["Host"]},
+			Plugin:              row["plugin"],
+			PasswordExpired:     row["password_expired"] == "Y",
+			PasswordLastChanged: row["password_last_changed"],
+			PasswordLifetime:    -1,
+			Locked:              row["account_locked"] == "Y",
+		}
+		if row["password_lifetime"] != "" {
+			account.PasswordLifetime = getInt(row["password_lifetime"])
+		}
+		if grants, err := GetGrants(endpoint, account.Account); err != nil {
+			account.Error = err
+		} else {
+			account.Grants, account.Roles = parseGrants(grants)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// CompareAccounts reads the accounts of the endpoints in parallel and reports the ones not present on all of them.
+func CompareAccounts(endpoints []string) (AccountDiffReport, error) {
+	report := AccountDiffReport{Errors: make(map[string]error)}
+	if len(endpoints) < 2 {
+		return report, errTooFewEndpoints
+	}
+	for _, endpoint := range endpoints {
+		if _, exists := getInstance(endpoint); !exists {
+			return report, errNotRegistered
+		}
+	}
+
+	accounts := make(map[string]map[Account]bool)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			dataSet, err := readDataSet(endpoint, "SELECT User, Host FROM mysql.user")
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				report.Errors[endpoint] = err
+				return
+			}
+			accounts[endpoint] = make(map[Account]bool)
+			for _, row := range dataSet {
+				accounts[endpoint][Account{User: row["User"], Host: row["Host"]}] = true
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	all := make(map[Account]bool)
+	for _, set := range accounts {
+		for account := range set {
+			all[account] = true
+		}
+	}
+	for account := range all {
+		diff := AccountDiff{Account: account}
+		for _, endpoint := range endpoints {
+			set, ok := accounts[endpoint]
+			if !ok {
+				continue
+			}
+			if set[account] {
+				diff.Present = append(diff.Present, endpoint)
+			} else {
+				diff.Missing = append(diff.Missing, endpoint)
+			}
+		}
+		if len(diff.Missing) > 0 {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+	sort.Sort(accountDiffs(report.Diffs))
+	return report, nil
+}
+
+type accountDiffs []AccountDiff
+
+func (diffs accountDiffs) Len() int      { return len(diffs) }
+func (diffs accountDiffs) Swap(i, j int) { diffs[i], diffs[j] = diffs[j], diffs[i] }
+func (diffs accountDiffs) Less(i, j int) bool {
+	if diffs[i].User != diffs[j].User {
+		return diffs[i].User < diffs[j].User
+	}
+	return diffs[i].Host < diffs[j].Host
+}
+
+// parseGrants parses the lines of "SHOW GRANTS" into privilege grants and granted roles.
+// The PROXY grants are ignored.
+func parseGrants(lines []string) ([]AccountGrant, []string) {
+	var grants []AccountGrant
+	var roles []string
+	for _, line := range lines {
+		if matches := grantLineExp.FindStringSubmatch(line); len(matches) == 4 {
+			grant := AccountGrant{
+				Privileges:  splitPrivileges(matches[1]),
+				Level:       matches[2],
+				GrantOption: strings.Contains(matches[3], " WITH GRANT OPTION"),
+			}
+			if grant.Privileges[0] != "PROXY" {
+				grants = append(grants, grant)
+			}
+		} else if strings.HasPrefix(line, "GRANT ") {
+			if parts := strings.SplitN(line[len("GRANT "):], " TO ", 2); len(parts) == 2 {
+				roles = append(roles, splitPrivileges(parts[0])...)
+			}
+		}
+	}
+	return grants, roles
+}
+
+// splitPrivileges splits the comma-separated privileges, keeping the column lists in parentheses.
+func splitPrivileges(s string) []string {
+	var result []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(result, strings.TrimSpace(s[start:]))
+}
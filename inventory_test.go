@@ -0,0 +1,86 @@
+package msops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGrants(t *testing.T) {
+	grants, roles := parseGrants([]string{
+		"GRANT RELOAD, PROCESS ON *.* TO 'dba'@'%' IDENTIFIED BY PASSWORD '*2F0D5E8B6D1B8D2A4B0A0B8F5B1A9C6E1E1C2E3F'",
+		"GRANT SELECT (`id`, `name`), INSERT ON `data_test`.`tbl_test` TO 'dba'@'%' WITH GRANT OPTION",
+		"GRANT EXECUTE ON PROCEDURE `data_test`.`proc` TO 'dba'@'%'",
+		"GRANT PROXY ON ''@'' TO 'root'@'localhost' WITH GRANT OPTION",
+		"GRANT `app_read`@`%`,`app_write`@`%` TO `app`@`%`",
+	})
+	expected := []AccountGrant{
+		{Privileges: []string{"RELOAD", "PROCESS"}, Level: "*.*"},
+		{Privileges: []string{"SELECT (`id`, `name`)", "INSERT"}, Level: "`data_test`.`tbl_test`", GrantOption: true},
+		{Privileges: []string{"EXECUTE"}, Level: "PROCEDURE `data_test`.`proc`"},
+	}
+	if !reflect.DeepEqual(grants, expected) {
+		t.Errorf("Test parseGrants failed: actual %+v, expected %+v", grants, expected)
+	}
+	if !reflect.DeepEqual(roles, []string{"`app_read`@`%`", "`app_write`@`%`"}) {
+		t.Errorf("Test parseGrants roles failed: actual %v", roles)
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	if _, err := ListAccounts(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test ListAccounts unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if err := execute(testEndpoint1, "CREATE USER ''@'localhost'"); err != nil {
+		t.Fatalf("Test ListAccounts create anonymous account error: %s", err.Error())
+	}
+	defer execute(testEndpoint1, "DROP USER ''@'localhost'")
+	accounts, err := ListAccounts(testEndpoint1)
+	if err != nil {
+		t.Fatalf("Test ListAccounts error: %s", err.Error())
+	}
+	var replFound, anonymousFound bool
+	for _, account := range accounts {
+		if account.Error != nil {
+			t.Errorf("Test ListAccounts %s error: %s", account.String(), account.Error.Error())
+		}
+		if account.User == testReplUser && account.Host == "%" {
+			replFound = true
+			if len(account.Grants) == 0 || account.Grants[0].Level != "*.*" {
+				t.Errorf("Test ListAccounts failed: unexpected grants of repl %+v", account.Grants)
+			}
+		}
+		if account.User == "" && account.Host == "localhost" {
+			anonymousFound = true
+		}
+	}
+	if !replFound || !anonymousFound {
+		t.Error("Test ListAccounts failed: repl user or anonymous user not found")
+	}
+}
+
+func TestCompareAccounts(t *testing.T) {
+	if _, err := CompareAccounts([]string{testEndpoint1}); err != errTooFewEndpoints {
+		t.Error("Test CompareAccounts one endpoint error: should return errTooFewEndpoints")
+	}
+	if _, err := CompareAccounts([]string{testEndpoint1, unregisteredEndpoint}); err != errNotRegistered {
+		t.Error("Test CompareAccounts unregisteredEndpoint error: should return errNotRegistered")
+	}
+	account := Account{User: "msops_inventory", Host: "%"}
+	if err := CreateUser(testEndpoint2, account, "test"); err != nil {
+		t.Fatalf("Test CreateUser error: %s", err.Error())
+	}
+	defer DropUser(testEndpoint2, account)
+	report, err := CompareAccounts([]string{testEndpoint1, testEndpoint2})
+	if err != nil {
+		t.Fatalf("Test CompareAccounts error: %s", err.Error())
+	}
+	for _, diff := range report.Diffs {
+		if diff.Account == account {
+			if !reflect.DeepEqual(diff.Present, []string{testEndpoint2}) || !reflect.DeepEqual(diff.Missing, []string{testEndpoint1}) {
+				t.Errorf("Test CompareAccounts failed: %+v", diff)
+			}
+			return
+		}
+	}
+	t.Error("Test CompareAccounts failed: the account only on testEndpoint2 isn't reported")
+}
@@ -0,0 +1,182 @@
+package msops
+
+import (
+	"regexp"
+	"strings"
+)
+
+// KillFilter describes the processes to be killed by KillProcessesByFilter.
+//
+// Empty fields match any process. A process is killed only if it matches all the non-empty fields.
+type KillFilter struct {
+	Users    []string
+	DBs      []string
+	Commands []string
+	States   []string
+
+	// HostPattern and InfoPattern are regular expressions matching 'Host' and 'Info' of the process.
+	HostPattern string
+	InfoPattern string
+
+	// MinTime is the minimum 'Time' in seconds of the process.
+	MinTime int
+
+	// ExcludeUsers are never killed.
+	ExcludeUsers []string
+
+	// IncludeSystem allows killing system threads, replication threads and the event scheduler,
+	// which are excluded by default.
+	IncludeSystem bool
+
+	// IncludeSelf allows killing the connection msops reads the processlist with, which is excluded by default.
+	// The other connections of the registered dbaUser, e.g. other msops processes or operators sharing
+	// the account, are matched as usual unless ExcludeDBAUser is true.
+	IncludeSelf bool
+
+	// ExcludeDBAUser excludes all the connections of the registered dbaUser.
+	ExcludeDBAUser bool
+
+	// KillQuery uses "KILL QUERY" to terminate the statement only, instead of "KILL CONNECTION".
+	KillQuery bool
+}
+
+// KillResult represents the result of killing one process.
+type KillResult struct {
+	Process Process
+	Error   error
+}
+
+// systemUsers are the users of the threads created by the server itself.
+var systemUsers = []string{"system user", "event_scheduler"}
+
+// replicationCommands are the commands of the threads serving the replicas.
+var replicationCommands = []string{"Binlog Dump", "Binlog Dump GTID", "Daemon"}
+
+// KillProcess executes "KILL CONNECTION id" at the endpoint, or "KILL QUERY id" if killQuery is true.
+func KillProcess(endpoint string, id int, killQuery bool) error {
+	if killQuery {
+		return execute(endpoint, "KILL QUERY ?", id)
+	}
+	return execute(endpoint, "KILL CONNECTION ?", id)
+}
+
+// KillProcessesByFilter kills the processes matching filter and returns the result of each killed process.
+//
+// Unlike KillProcesses, errors of killing one process are reported in the results
+// and don't stop killing the others.
+func KillProcessesByFilter(endpoint string, filter KillFilter) ([]KillResult, error) {
+	var inst *Instance
+	var exists bool
+	if inst, exists = getInstance(endpoint); !exists {
+		return nil, errNotRegistered
+	}
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	var processes []Process
+	var selfID int
+	var err error
+	if processes, selfID, err = readKillableProcesses(endpoint); err != nil {
+		return nil, err
+	}
+	var results []KillResult
+	for _, process := range processes {
+		if !filter.matches(process, selfID, inst.dbaUser) {
+			continue
+		}
+		results = append(results, KillResult{
+			Process: process,
+			Error:   KillProcess(endpoint, process.ID, filter.KillQuery),
+		})
+	}
+	return results, nil
+}
+
+// validate checks whether the patterns of the filter are valid regular expressions.
+func (filter KillFilter) validate() error {
+	for _, pattern := range []string{filter.HostPattern, filter.InfoPattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readKillableProcesses reads the processlist of the endpoint together with the id of the connection reading it.
+func readKillableProcesses(endpoint string) ([]Process, int, error) {
+	dataSet, err := readDataSet(endpoint, "SELECT ID, USER, HOST, DB, COMMAND, TIME, STATE, INFO, "+
+		"CONNECTION_ID() AS SELF_ID FROM information_schema.PROCESSLIST")
+	if err != nil {
+		return nil, 0, err
+	}
+	var selfID int
+	processes := make([]Process, 0, len(dataSet))
+	for _, row := range dataSet {
+		selfID = getInt(row["SELF_ID"])
+		processes = append(processes, Process{
+			ID:      getInt(row["ID"]),
+			User:    row["USER"],
+			Host:    row["HOST"],
+			DB:      row["DB"],
+			Command: row["COMMAND"],
+			Time:    getInt(row["TIME"]),
+			State:   row["STATE"],
+			Info:    row["INFO"],
+		})
+	}
+	return processes, selfID, nil
+}
+
+// matches reports whether the process should be killed.
+// selfID is the connection id of msops, and dbaUser is the user msops connects with.
+func (filter KillFilter) matches(process Process, selfID int, dbaUser string) bool {
+	if !filter.IncludeSystem && isSystemProcess(process) {
+		return false
+	}
+	if !filter.IncludeSelf && process.ID == selfID {
+		return false
+	}
+	if filter.ExcludeDBAUser && process.User == dbaUser {
+		return false
+	}
+	if contains(filter.ExcludeUsers, process.User) {
+		return false
+	}
+	if (len(filter.Users) > 0 && !contains(filter.Users, process.User)) ||
+		(len(filter.DBs) > 0 && !contains(filter.DBs, process.DB)) ||
+		(len(filter.Commands) > 0 && !containsFold(filter.Commands, process.Command)) ||
+		(len(filter.States) > 0 && !containsFold(filter.States, process.State)) {
+		return false
+	}
+	if process.Time < filter.MinTime {
+		return false
+	}
+	if (filter.HostPattern != "" && !match(filter.HostPattern, process.Host)) ||
+		(filter.InfoPattern != "" && !match(filter.InfoPattern, process.Info)) {
+		return false
+	}
+	return true
+}
+
+// isSystemProcess reports whether the process is a system thread, a replication thread or the event scheduler.
+func isSystemProcess(process Process) bool {
+	return contains(systemUsers, process.User) || contains(replicationCommands, process.Command)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
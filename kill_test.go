@@ -0,0 +1,68 @@
+package msops
+
+import "testing"
+
+func TestKillFilterMatches(t *testing.T) {
+	processes := map[string]Process{
+		"system":  {ID: 1, User: "system user", Command: "Connect", Time: 100},
+		"dump":    {ID: 2, User: "repl", Command: "Binlog Dump", Time: 100},
+		"self":    {ID: 3, User: testDBAUser, Command: "Query", Time: 100, Info: "SHOW PROCESSLIST"},
+		"dba":     {ID: 7, User: testDBAUser, Host: "10.0.0.1:6000", Command: "Query", Time: 100, Info: "SELECT SLEEP(100)"},
+		"report":  {ID: 4, User: "report", Host: "10.0.0.1:5123", DB: "data_test", Command: "Query", Time: 100, Info: "SELECT * FROM tbl_test"},
+		"short":   {ID: 5, User: "report", Host: "10.0.0.1:5124", DB: "data_test", Command: "Query", Time: 1, Info: "SELECT 1"},
+		"sleeper": {ID: 6, User: "app", Host: "10.0.0.2:5123", Command: "Sleep", Time: 100},
+	}
+	filter := KillFilter{HostPattern: `^10\.0\.0\.1:`, MinTime: 10, InfoPattern: "(?i)^select"}
+	for name, process := range processes {
+		if expected := name == "report" || name == "dba"; filter.matches(process, 3, testDBAUser) != expected {
+			t.Errorf("Test KillFilter matches %s failed: expected %t", name, expected)
+		}
+	}
+
+	filter = KillFilter{IncludeSystem: true, IncludeSelf: true, ExcludeUsers: []string{"app"}}
+	for name, process := range processes {
+		if expected := name != "sleeper"; filter.matches(process, 3, testDBAUser) != expected {
+			t.Errorf("Test KillFilter matches all %s failed: expected %t", name, expected)
+		}
+	}
+
+	filter = KillFilter{ExcludeDBAUser: true}
+	for name, process := range processes {
+		if expected := name == "report" || name == "short" || name == "sleeper"; filter.matches(process, 3, testDBAUser) != expected {
+			t.Errorf("Test KillFilter matches ExcludeDBAUser %s failed: expected %t", name, expected)
+		}
+	}
+}
+
+func TestKillProcessesByFilter(t *testing.T) {
+	if _, err := KillProcessesByFilter(unregisteredEndpoint, KillFilter{}); err != errNotRegistered {
+		t.Error("Test KillProcessesByFilter unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := KillProcessesByFilter(testEndpoint1, KillFilter{InfoPattern: "("}); err == nil {
+		t.Error("Test KillProcessesByFilter invalid pattern error: should return error")
+	}
+	if _, err := KillProcessesByFilter(badEndpoint, KillFilter{}); err == nil {
+		t.Error("Test KillProcessesByFilter badEndpoint error: should return error")
+	}
+	if results, err := KillProcessesByFilter(testEndpoint1, KillFilter{ExcludeDBAUser: true}); err != nil {
+		t.Errorf("Test KillProcessesByFilter error: %s", err.Error())
+	} else if len(results) != 0 {
+		t.Errorf("Test KillProcessesByFilter failed: actual %d processes killed, expected 0", len(results))
+	} else if CheckInstance(testEndpoint1) != InstanceOK {
+		t.Error("Test KillProcessesByFilter failed: own connection is killed")
+	}
+}
+
+func TestIsSystemProcess(t *testing.T) {
+	processes := map[string]Process{
+		"sql thread": {ID: 1, User: "system user", Command: "Connect", State: "Waiting for an event from Coordinator"},
+		"dump":       {ID: 2, User: "repl", Command: "Binlog Dump GTID"},
+		"scheduler":  {ID: 3, User: "event_scheduler", Command: "Daemon"},
+		"idle trx":   {ID: 4, User: "app", Command: "Sleep"},
+	}
+	for name, process := range processes {
+		if expected := name != "idle trx"; isSystemProcess(process) != expected {
+			t.Errorf("Test isSystemProcess %s failed: expected %t", name, expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package msops
+
+import (
+	"errors"
+	"sort"
+)
+
+// LockWait represents one InnoDB transaction waiting for a lock held by another transaction.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.7/en/innodb-information-schema-examples.html
+type LockWait struct {
+	WaitingTrxID    string
+	WaitingThreadID int
+	WaitingQuery    string
+	WaitSeconds     int
+
+	BlockingTrxID    string
+	BlockingThreadID int
+	BlockingQuery    string
+
+	LockedTable string
+	LockedIndex string
+	LockMode    string
+}
+
+var errSystemThread = errors.New("the system or replication thread is not killed")
+
+const (
+	innodbLockWaitsQuery = "SELECT r.trx_id AS waiting_trx_id, r.trx_mysql_thread_id AS waiting_thread, " +
+		"r.trx_query AS waiting_query, TIMESTAMPDIFF(SECOND, r.trx_wait_started, NOW()) AS wait_seconds, " +
+		"b.trx_id AS blocking_trx_id, b.trx_mysql_thread_id AS blocking_thread, b.trx_query AS blocking_query, " +
+		"l.lock_table AS locked_table, l.lock_index AS locked_index, l.lock_mode AS lock_mode " +
+		"FROM information_schema.INNODB_LOCK_WAITS w " +
+		"JOIN information_schema.INNODB_TRX b ON b.trx_id = w.blocking_trx_id " +
+		"JOIN information_schema.INNODB_TRX r ON r.trx_id = w.requesting_trx_id " +
+		"LEFT JOIN information_schema.INNODB_LOCKS l ON l.lock_id = w.requested_lock_id"
+	dataLockWaitsQuery = "SELECT r.trx_id AS waiting_trx_id, r.trx_mysql_thread_id AS waiting_thread, " +
+		"r.trx_query AS waiting_query, TIMESTAMPDIFF(SECOND, r.trx_wait_started, NOW()) AS wait_seconds, " +
+		"b.trx_id AS blocking_trx_id, b.trx_mysql_thread_id AS blocking_thread, b.trx_query AS blocking_query, " +
+		"CONCAT(l.OBJECT_SCHEMA, '.', l.OBJECT_NAME) AS locked_table, l.INDEX_NAME AS locked_index, l.LOCK_MODE AS lock_mode " +
+		"FROM performance_schema.data_lock_waits w " +
+		"JOIN information_schema.INNODB_TRX b ON b.trx_id = w.BLOCKING_ENGINE_TRANSACTION_ID " +
+		"JOIN information_schema.INNODB_TRX r ON r.trx_id = w.REQUESTING_ENGINE_TRANSACTION_ID " +
+		"LEFT JOIN performance_schema.data_locks l ON l.ENGINE_LOCK_ID = w.REQUESTING_ENGINE_LOCK_ID"
+)
+
+// GetLockWaits returns the waiting and blocking transaction pairs of the endpoint.
+//
+// information_schema.INNODB_LOCK_WAITS is read before MySQL 8.0, and performance_schema.data_lock_waits is read since then.
+func GetLockWaits(endpoint string) ([]LockWait, error) {
+	var version serverVersion
+	var dataSet []map[string]string
+	var err error
+	if version, err = getVersion(endpoint); err != nil {
+		return nil, err
+	}
+	query := innodbLockWaitsQuery
+	if version.atLeast(8, 0, 1) {
+		query = dataLockWaitsQuery
+	}
+	if dataSet, err = readDataSet(endpoint, query); err != nil {
+		return nil, err
+	}
+	waits := make([]LockWait, 0, len(dataSet))
+	for _, row := range dataSet {
+		waits = append(waits, LockWait{
+			WaitingTrxID:     row["waiting_trx_id"],
+			WaitingThreadID:  getInt(row["waiting_thread"]),
+			WaitingQuery:     row["waiting_query"],
+			WaitSeconds:      getInt(row["wait_seconds"]),
+			BlockingTrxID:    row["blocking_trx_id"],
+			BlockingThreadID: getInt(row["blocking_thread"]),
+			BlockingQuery:    row["blocking_query"],
+			LockedTable:      row["locked_table"],
+			LockedIndex:      row["locked_index"],
+			LockMode:         row["lock_mode"],
+		})
+	}
+	return waits, nil
+}
+
+// RootBlockers returns the processlist ids of the threads at the root of the blocking chains in waits,
+// i.e. the threads blocking others without waiting for any lock themselves.
+func RootBlockers(waits []LockWait) []int {
+	waiting := make(map[int]bool)
+	for _, wait := range waits {
+		waiting[wait.WaitingThreadID] = true
+	}
+	rootSet := make(map[int]bool)
+	for _, wait := range waits {
+		if !waiting[wait.BlockingThreadID] {
+			rootSet[wait.BlockingThreadID] = true
+		}
+	}
+	roots := make([]int, 0, len(rootSet))
+	for id := range rootSet {
+		roots = append(roots, id)
+	}
+	sort.Ints(roots)
+	return roots
+}
+
+// KillRootBlockers kills the connections of the root blockers of the endpoint and returns the result of each.
+//
+// Connections are always killed instead of queries, because an idle transaction keeps its locks
+// until the connection is closed. The system and replication threads, e.g. the SQL thread of a replica,
+// are never killed and reported with errSystemThread.
+func KillRootBlockers(endpoint string) ([]KillResult, error) {
+	var waits []LockWait
+	var processes []Process
+	var err error
+	if waits, err = GetLockWaits(endpoint); err != nil {
+		return nil, err
+	}
+	roots := RootBlockers(waits)
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	if processes, err = GetProcessList(endpoint); err != nil {
+		return nil, err
+	}
+	processMap := make(map[int]Process, len(processes))
+	for _, process := range processes {
+		processMap[process.ID] = process
+	}
+	results := make([]KillResult, 0, len(roots))
+	for _, id := range roots {
+		process, exists := processMap[id]
+		if !exists {
+			process = Process{ID: id}
+		}
+		if isSystemProcess(process) {
+			results = append(results, KillResult{Process: process, Error: errSystemThread})
+			continue
+		}
+		results = append(results, KillResult{
+			Process: process,
+			Error:   KillProcess(endpoint, id, false),
+		})
+	}
+	return results, nil
+}
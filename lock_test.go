@@ -0,0 +1,64 @@
+package msops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRootBlockers(t *testing.T) {
+	waits := []LockWait{
+		{WaitingThreadID: 3, BlockingThreadID: 2},
+		{WaitingThreadID: 2, BlockingThreadID: 1},
+		{WaitingThreadID: 4, BlockingThreadID: 1},
+		{WaitingThreadID: 6, BlockingThreadID: 5},
+	}
+	if roots := RootBlockers(waits); !reflect.DeepEqual(roots, []int{1, 5}) {
+		t.Errorf("Test RootBlockers failed: actual %v, expected [1 5]", roots)
+	}
+	if roots := RootBlockers(nil); len(roots) != 0 {
+		t.Errorf("Test RootBlockers failed: actual %v, expected empty", roots)
+	}
+}
+
+func TestGetLockWaitsAndKillRootBlockers(t *testing.T) {
+	if _, err := GetLockWaits(badEndpoint); err == nil {
+		t.Error("Get badEndpoint lock waits should cause error")
+	}
+	conn := connectionPool[testEndpoint1].connection
+	blocker, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("Test GetLockWaits begin blocker error: %s", err.Error())
+	}
+	defer blocker.Rollback()
+	if _, err = blocker.Exec("SELECT * FROM data_test.tbl_test WHERE id = 1 FOR UPDATE"); err != nil {
+		t.Fatalf("Test GetLockWaits lock row error: %s", err.Error())
+	}
+	waiter, err := conn.Begin()
+	if err != nil {
+		t.Fatalf("Test GetLockWaits begin waiter error: %s", err.Error())
+	}
+	done := make(chan struct{})
+	go func() {
+		waiter.Exec("SELECT * FROM data_test.tbl_test WHERE id = 1 FOR UPDATE")
+		waiter.Rollback()
+		close(done)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	if waits, err := GetLockWaits(testEndpoint1); err != nil {
+		t.Errorf("Test GetLockWaits error: %s", err.Error())
+	} else if len(waits) != 1 {
+		t.Errorf("Test GetLockWaits failed: actual %d lock waits, expected 1", len(waits))
+	}
+	if results, err := KillRootBlockers(testEndpoint1); err != nil {
+		t.Errorf("Test KillRootBlockers error: %s", err.Error())
+	} else if len(results) != 1 || results[0].Error != nil {
+		t.Errorf("Test KillRootBlockers failed: actual results %+v, expected 1 killed blocker", results)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Error("Test KillRootBlockers failed: waiter is still blocked")
+	}
+}
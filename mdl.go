@@ -0,0 +1,212 @@
+package msops
+
+// MetadataLock represents one row data of performance_schema.metadata_locks, which is available since MySQL 5.7.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.7/en/performance-schema-metadata-locks-table.html
+type MetadataLock struct {
+	ObjectType    string
+	ObjectSchema  string
+	ObjectName    string
+	LockType      string
+	LockDuration  string
+	LockStatus    string
+	OwnerThreadID int
+	ProcessID     int
+}
+
+// MetadataLockHolder represents a session holding or requesting a metadata lock which blocks a pending one.
+//
+// A pending request blocks the requests of lower priority queued after it, e.g. the pending EXCLUSIVE lock of
+// "ALTER TABLE" blocks the following SELECTs, in which case Lock.LockStatus is "PENDING".
+type MetadataLockHolder struct {
+	Process Process
+	Lock    MetadataLock
+
+	// IdleInTransaction implies that the session is sleeping with an open transaction,
+	// which holds the metadata locks until the transaction ends.
+	IdleInTransaction bool
+}
+
+// MetadataLockWait represents a session waiting for a metadata lock and the sessions blocking it.
+type MetadataLockWait struct {
+	Waiting  Process
+	Lock     MetadataLock
+	Blockers []MetadataLockHolder
+
+	// RootBlockers are the sessions holding granted locks at the root of the blocking chains,
+	// following the blockers waiting themselves, e.g. the idle transaction blocking an "ALTER TABLE"
+	// which blocks the SELECTs.
+	RootBlockers []MetadataLockHolder
+}
+
+const metadataLocksQuery = "SELECT m.OBJECT_TYPE, m.OBJECT_SCHEMA, m.OBJECT_NAME, m.LOCK_TYPE, m.LOCK_DURATION, " +
+	"m.LOCK_STATUS, m.OWNER_THREAD_ID, t.PROCESSLIST_ID " +
+	"FROM performance_schema.metadata_locks m " +
+	"JOIN performance_schema.threads t ON t.THREAD_ID = m.OWNER_THREAD_ID " +
+	"WHERE t.PROCESSLIST_ID IS NOT NULL"
+
+// mdlCompatible maps a requested metadata lock type to the granted types it's compatible with.
+//
+// It's the compatibility matrix of object locks in sql/mdl.cc of MySQL server.
+var mdlCompatible = map[string][]string{
+	"SHARED":                {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE", "SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE"},
+	"SHARED_HIGH_PRIO":      {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE", "SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE"},
+	"SHARED_READ":           {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE", "SHARED_READ_ONLY", "SHARED_NO_WRITE"},
+	"SHARED_WRITE":          {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE"},
+	"SHARED_WRITE_LOW_PRIO": {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE"},
+	"SHARED_UPGRADABLE":     {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_READ_ONLY"},
+	"SHARED_READ_ONLY":      {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_UPGRADABLE", "SHARED_READ_ONLY", "SHARED_NO_WRITE"},
+	"SHARED_NO_WRITE":       {"SHARED", "SHARED_HIGH_PRIO", "SHARED_READ", "SHARED_READ_ONLY"},
+	"SHARED_NO_READ_WRITE":  {"SHARED", "SHARED_HIGH_PRIO"},
+	"EXCLUSIVE":             {},
+}
+
+// mdlPendingIncompatible maps a requested metadata lock type to the pending types of higher priority
+// which it's queued after, even if it's compatible with all the granted locks.
+//
+// It's the compatibility matrix of the waiting requests of object locks in sql/mdl.cc of MySQL server.
+var mdlPendingIncompatible = map[string][]string{
+	"SHARED":                {"EXCLUSIVE"},
+	"SHARED_READ":           {"SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_WRITE":          {"SHARED_READ_ONLY", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_WRITE_LOW_PRIO": {"SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_UPGRADABLE":     {"EXCLUSIVE"},
+	"SHARED_READ_ONLY":      {"SHARED_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_NO_WRITE":       {"EXCLUSIVE"},
+	"SHARED_NO_READ_WRITE":  {"EXCLUSIVE"},
+}
+
+// GetMetadataLocks executes "SELECT ... FROM performance_schema.metadata_locks" and returns the resultset
+// of the locks owned by user sessions.
+//
+// The instrument 'wait/lock/metadata/sql/mdl' should be enabled, which is disabled by default before MySQL 8.0.
+func GetMetadataLocks(endpoint string) ([]MetadataLock, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, metadataLocksQuery); err != nil {
+		return nil, err
+	}
+	locks := make([]MetadataLock, 0, len(dataSet))
+	for _, row := range dataSet {
+		locks = append(locks, MetadataLock{
+			ObjectType:    row["OBJECT_TYPE"],
+			ObjectSchema:  row["OBJECT_SCHEMA"],
+			ObjectName:    row["OBJECT_NAME"],
+			LockType:      row["LOCK_TYPE"],
+			LockDuration:  row["LOCK_DURATION"],
+			LockStatus:    row["LOCK_STATUS"],
+			OwnerThreadID: getInt(row["OWNER_THREAD_ID"]),
+			ProcessID:     getInt(row["PROCESSLIST_ID"]),
+		})
+	}
+	return locks, nil
+}
+
+// GetMetadataLockWaits returns every session waiting for a metadata lock of the endpoint,
+// with the sessions holding the conflicting locks.
+func GetMetadataLockWaits(endpoint string) ([]MetadataLockWait, error) {
+	var locks []MetadataLock
+	var processes []Process
+	var err error
+	if locks, err = GetMetadataLocks(endpoint); err != nil {
+		return nil, err
+	}
+	if processes, err = GetFullProcessList(endpoint, ProcessListInformationSchema); err != nil {
+		return nil, err
+	}
+	return findMetadataLockWaits(locks, processes), nil
+}
+
+// findMetadataLockWaits matches the pending locks with the conflicting granted locks on the same object,
+// and the pending locks of higher priority queued before them. The requests are ordered by 'Time' of the processes,
+// since metadata_locks doesn't record when the locks are requested.
+func findMetadataLockWaits(locks []MetadataLock, processes []Process) []MetadataLockWait {
+	processMap := make(map[int]Process, len(processes))
+	for _, process := range processes {
+		processMap[process.ID] = process
+	}
+	getProcess := func(id int) Process {
+		if process, exists := processMap[id]; exists {
+			return process
+		}
+		return Process{ID: id}
+	}
+	sameObject := func(a, b MetadataLock) bool {
+		return a.ObjectType == b.ObjectType && a.ObjectSchema == b.ObjectSchema && a.ObjectName == b.ObjectName
+	}
+	var waits []MetadataLockWait
+	for _, pending := range locks {
+		if pending.LockStatus != "PENDING" {
+			continue
+		}
+		waiting := getProcess(pending.ProcessID)
+		wait := MetadataLockWait{Waiting: waiting, Lock: pending}
+		for _, other := range locks {
+			if other.ProcessID == pending.ProcessID || !sameObject(pending, other) {
+				continue
+			}
+			switch other.LockStatus {
+			case "GRANTED":
+				if mdlIsCompatible(pending.LockType, other.LockType) {
+					continue
+				}
+			case "PENDING":
+				if !contains(mdlPendingIncompatible[pending.LockType], other.LockType) ||
+					getProcess(other.ProcessID).Time < waiting.Time {
+					continue
+				}
+			default:
+				continue
+			}
+			holder := getProcess(other.ProcessID)
+			wait.Blockers = append(wait.Blockers, MetadataLockHolder{
+				Process:           holder,
+				Lock:              other,
+				IdleInTransaction: holder.Command == "Sleep" && holder.TrxState != "",
+			})
+		}
+		waits = append(waits, wait)
+	}
+
+	waitsByProcess := make(map[int][]int)
+	for i, wait := range waits {
+		waitsByProcess[wait.Waiting.ID] = append(waitsByProcess[wait.Waiting.ID], i)
+	}
+	for i := range waits {
+		visited := map[int]bool{waits[i].Waiting.ID: true}
+		var follow func(blockers []MetadataLockHolder)
+		follow = func(blockers []MetadataLockHolder) {
+			for _, blocker := range blockers {
+				if visited[blocker.Process.ID] {
+					continue
+				}
+				visited[blocker.Process.ID] = true
+				var next []MetadataLockHolder
+				for _, j := range waitsByProcess[blocker.Process.ID] {
+					next = append(next, waits[j].Blockers...)
+				}
+				if len(next) > 0 {
+					follow(next)
+					continue
+				}
+				// The chain ends at a granted lock, or a pending one whose blockers are unknown.
+				waits[i].RootBlockers = append(waits[i].RootBlockers, blocker)
+			}
+		}
+		follow(waits[i].Blockers)
+	}
+	return waits
+}
+
+// mdlIsCompatible reports whether the requested lock type can be granted along with the granted one.
+// Unknown lock types are considered compatible.
+func mdlIsCompatible(requested, granted string) bool {
+	compatible, known := mdlCompatible[requested]
+	if !known {
+		return true
+	}
+	if _, known = mdlCompatible[granted]; !known {
+		return true
+	}
+	return contains(compatible, granted)
+}
@@ -0,0 +1,60 @@
+package msops
+
+import "testing"
+
+func TestFindMetadataLockWaits(t *testing.T) {
+	locks := []MetadataLock{
+		{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "tbl_test", LockType: "SHARED_READ", LockStatus: "GRANTED", ProcessID: 10},
+		{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "tbl_test", LockType: "SHARED_UPGRADABLE", LockStatus: "GRANTED", ProcessID: 11},
+		{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "tbl_test", LockType: "EXCLUSIVE", LockStatus: "PENDING", ProcessID: 11},
+		{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "other", LockType: "SHARED_WRITE", LockStatus: "GRANTED", ProcessID: 12},
+		{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "tbl_test", LockType: "SHARED_READ", LockStatus: "PENDING", ProcessID: 13},
+	}
+	processes := []Process{
+		{ID: 10, Command: "Sleep", TrxState: "RUNNING"},
+		{ID: 11, Command: "Query", Time: 30, State: "Waiting for table metadata lock", Info: "ALTER TABLE tbl_test ADD COLUMN c int"},
+		{ID: 13, Command: "Query", Time: 5, State: "Waiting for table metadata lock", Info: "SELECT * FROM tbl_test"},
+	}
+	waits := findMetadataLockWaits(locks, processes)
+	if len(waits) != 2 {
+		t.Fatalf("Test findMetadataLockWaits failed: actual %d waits, expected 2", len(waits))
+	}
+	if blockers := waits[0].Blockers; waits[0].Waiting.ID != 11 || len(blockers) != 1 ||
+		blockers[0].Process.ID != 10 || !blockers[0].IdleInTransaction {
+		t.Errorf("Test findMetadataLockWaits failed: unexpected wait of ALTER TABLE %+v", waits[0])
+	}
+	if roots := waits[0].RootBlockers; len(roots) != 1 || roots[0].Process.ID != 10 {
+		t.Errorf("Test findMetadataLockWaits failed: unexpected root blockers of ALTER TABLE %+v", roots)
+	}
+	if blockers := waits[1].Blockers; waits[1].Waiting.ID != 13 || len(blockers) != 1 ||
+		blockers[0].Process.ID != 11 || blockers[0].Lock.LockType != "EXCLUSIVE" {
+		t.Errorf("Test findMetadataLockWaits failed: SELECT should be blocked by the pending EXCLUSIVE lock of ALTER TABLE, actual %+v", blockers)
+	}
+	if roots := waits[1].RootBlockers; len(roots) != 1 || roots[0].Process.ID != 10 || !roots[0].IdleInTransaction {
+		t.Errorf("Test findMetadataLockWaits failed: SELECT should be blocked by the idle transaction at root, actual %+v", roots)
+	}
+
+	// The SELECT requested before the ALTER TABLE is blocked by the granted locks only.
+	processes[2].Time = 60
+	if waits = findMetadataLockWaits(locks, processes); len(waits[1].Blockers) != 0 {
+		t.Errorf("Test findMetadataLockWaits failed: SELECT queued before ALTER TABLE, actual blockers %+v", waits[1].Blockers)
+	}
+
+	// The SHARED lock is queued after the pending EXCLUSIVE lock, though it's compatible with all the granted locks.
+	locks = append(locks[:3], MetadataLock{ObjectType: "TABLE", ObjectSchema: "data_test", ObjectName: "tbl_test",
+		LockType: "SHARED", LockStatus: "PENDING", ProcessID: 14})
+	processes = append(processes[:2], Process{ID: 14, Command: "Query", Time: 5, State: "Waiting for table metadata lock"})
+	if waits = findMetadataLockWaits(locks, processes); len(waits) != 2 || waits[1].Waiting.ID != 14 ||
+		len(waits[1].Blockers) != 1 || waits[1].Blockers[0].Lock.LockType != "EXCLUSIVE" {
+		t.Errorf("Test findMetadataLockWaits failed: SHARED should be blocked by the pending EXCLUSIVE lock, actual %+v", waits)
+	}
+}
+
+func TestGetMetadataLockWaits(t *testing.T) {
+	if _, err := GetMetadataLockWaits(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test GetMetadataLockWaits unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := GetMetadataLocks(badEndpoint); err == nil {
+		t.Error("Get badEndpoint metadata locks should cause error")
+	}
+}
@@ -2,82 +2,100 @@ package msops
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 // ResetSlave executes "RESET SLAVE ALL" if resetAll is true.
 // Otherwise executes "RESET SLAVE".
 func ResetSlave(endpoint string, resetAll bool) error {
-	var slaveInst *Instance
-	var exists bool
-	var err error
-	if slaveInst, exists = connectionPool[endpoint]; !exists {
-		return errNotRegistered
-	}
 	if resetAll {
-		_, err = slaveInst.connection.Exec("RESET SLAVE ALL")
-	} else {
-		_, err = slaveInst.connection.Exec("RESET SLAVE")
+		return execute(endpoint, "RESET SLAVE ALL")
 	}
-	return err
+	return execute(endpoint, "RESET SLAVE")
 }
 
 // StartSlave executes "START SLAVE" at the endpoint.
 func StartSlave(endpoint string) error {
-	var slaveInst *Instance
-	var exists bool
-	if slaveInst, exists = connectionPool[endpoint]; !exists {
-		return errNotRegistered
-	}
-	_, err := slaveInst.connection.Exec("START SLAVE")
-	return err
+	return execute(endpoint, "START SLAVE")
 }
 
 // StopSlave executes "STOP SLAVE" at the endpoint.
 func StopSlave(endpoint string) error {
-	var slaveInst *Instance
-	var exists bool
-	if slaveInst, exists = connectionPool[endpoint]; !exists {
-		return errNotRegistered
-	}
-	_, err := slaveInst.connection.Exec("STOP SLAVE")
-	return err
+	return execute(endpoint, "STOP SLAVE")
 }
 
 // ChangeMasterTo makes slaveEndpoint as a slave of masterEndpoint from now on.
 // Use MASTER_AUTO_POSITION=1 instead of specifying the binlog file and position if useGTID is true.
 func ChangeMasterTo(slaveEndpoint, masterEndpoint string, useGTID bool) error {
-	var slaveInst, masterInst *Instance
+	return ChangeMasterToWithOptions(slaveEndpoint, masterEndpoint, ChangeMasterOptions{UseGTID: useGTID})
+}
+
+// ChangeMasterToWithOptions makes slaveEndpoint as a slave of masterEndpoint from now on,
+// appending the clauses described by opts to "CHANGE MASTER TO".
+//
+// If neither opts.UseGTID nor opts.MasterLogFile is set, the current binlog file and position of
+// masterEndpoint are used.
+//
+// If opts is invalid, an error will be returned without executing any statement.
+func ChangeMasterToWithOptions(slaveEndpoint, masterEndpoint string, opts ChangeMasterOptions) error {
+	var masterInst *Instance
 	var exists bool
 	var host, portStr string
 	var err error
 	var port int
-	if slaveInst, exists = connectionPool[slaveEndpoint]; !exists {
+	if _, exists = getInstance(slaveEndpoint); !exists {
 		return errNotRegistered
 	}
-	if masterInst, exists = connectionPool[masterEndpoint]; !exists {
+	if masterInst, exists = getInstance(masterEndpoint); !exists {
 		return errNotRegistered
 	}
+	if err = opts.validate(); err != nil {
+		return err
+	}
+	if opts.GetMasterPublicKey != nil {
+		if version, e := getVersion(slaveEndpoint); e != nil {
+			return e
+		} else if !version.atLeast(8, 0, 4) {
+			return errPublicKeyUnsupported
+		}
+	}
+	if opts.Validate {
+		if check, e := ValidateReplicationPair(slaveEndpoint, masterEndpoint); e != nil {
+			return e
+		} else if !check.OK() {
+			return fmt.Errorf("replication check failed: %s", strings.Join(check.Problems, "; "))
+		}
+	}
 	if host, portStr, err = net.SplitHostPort(masterEndpoint); err != nil {
 		return err
 	}
 	if port, err = strconv.Atoi(portStr); err != nil {
 		return err
 	}
-	if useGTID {
-		_, err = slaveInst.connection.Exec("CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?, MASTER_AUTO_POSITION=1",
-			host, port, masterInst.replUser, masterInst.replPassword)
+	clauses := []string{"MASTER_HOST=?", "MASTER_PORT=?", "MASTER_USER=?", "MASTER_PASSWORD=?"}
+	args := []interface{}{host, port, masterInst.replUser, replPasswordOf(masterEndpoint, masterInst)}
+	if opts.UseGTID {
+		clauses = append(clauses, "MASTER_AUTO_POSITION=1")
+	} else if opts.MasterLogFile != "" {
+		clauses = append(clauses, "MASTER_LOG_FILE=?", "MASTER_LOG_POS=?")
+		args = append(args, opts.MasterLogFile, opts.MasterLogPos)
 	} else if masterSt, e := GetMasterStatus(masterEndpoint); e != nil {
 		return e
 	} else {
-		_, err = slaveInst.connection.Exec("CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?, MASTER_LOG_FILE=?, MASTER_LOG_POS=?",
-			host, port, masterInst.replUser, masterInst.replPassword, masterSt.File, masterSt.Position)
+		clauses = append(clauses, "MASTER_LOG_FILE=?", "MASTER_LOG_POS=?")
+		args = append(args, masterSt.File, masterSt.Position)
 	}
-	return err
+	optClauses, optArgs := opts.clauses()
+	clauses = append(clauses, optClauses...)
+	args = append(args, optArgs...)
+	return execute(slaveEndpoint, "CHANGE MASTER TO "+strings.Join(clauses, ", "), args...)
 }
 
 // GetInnoDBStatus executes "SHOW engine InnoDB STATUS" and returns the 'Status' field.
@@ -136,6 +154,12 @@ func GetInnoDBStatus(endpoint string) (InnoDBStatus, error) {
 					innodbStatus.InnodbMutexOSWaits, _ = strconv.Atoi(matches[3])
 				}
 			}
+			if section == "TRANSACTIONS" {
+				matches := innodbHistoryListExp.FindStringSubmatch(line)
+				if len(matches) == 2 {
+					innodbStatus.InnodbHistoryListLength, _ = strconv.Atoi(matches[1])
+				}
+			}
 		}
 	}
 	return innodbStatus, nil
@@ -256,19 +280,26 @@ func GetGlobalVariables(endpoint, pattern string) (map[string]string, error) {
 }
 
 // SetGlobalVariable executes the statement 'SET GLOBAL key=value'.
+//
+// The variable should exist and be dynamic. value is validated and converted according to the type and range
+// of the variable, which are read from a bundled catalog of the common variables. The range is overridden
+// by performance_schema.variables_info on MySQL 8.0. See Unvalidated for the variables not in the catalog.
 func SetGlobalVariable(endpoint, key string, value interface{}) error {
-	var inst *Instance
-	var exists bool
-	if inst, exists = connectionPool[endpoint]; !exists {
+	if _, exists := getInstance(endpoint); !exists {
 		return errNotRegistered
 	}
 	if !globalKeyExp.MatchString(key) {
 		return errKeyInvalid
 	}
-	if _, err := inst.connection.Exec(fmt.Sprintf("SET GLOBAL %s=?", key), value); err != nil {
+	version, err := getVersion(endpoint)
+	if err != nil {
 		return err
 	}
-	return nil
+	assignment, err := prepareGlobalVariable(endpoint, key, value, version, false)
+	if err != nil {
+		return err
+	}
+	return execute(endpoint, fmt.Sprintf("SET GLOBAL %s=?", assignment.name), assignment.arg)
 }
 
 // GetProcessList executes "SHOW PROCESSLIST" and returns the resultset.
@@ -298,9 +329,7 @@ func GetProcessList(endpoint string) ([]Process, error) {
 
 // KillProcesses kills all the connection threads except the ones of whiteUsers.
 func KillProcesses(endpoint string, whiteUsers ...string) error {
-	var inst *Instance
-	var exists bool
-	if inst, exists = connectionPool[endpoint]; !exists {
+	if _, exists := getInstance(endpoint); !exists {
 		return errNotRegistered
 	}
 	var processes []Process
@@ -318,7 +347,7 @@ func KillProcesses(endpoint string, whiteUsers ...string) error {
 			}
 		}
 		if !isWhiteUser {
-			inst.connection.Exec("KILL ?", process.ID)
+			execute(endpoint, "KILL ?", process.ID)
 		}
 	}
 	return nil
@@ -328,7 +357,7 @@ func KillProcesses(endpoint string, whiteUsers ...string) error {
 func readDataSet(endpoint, query string, args ...interface{}) ([]map[string]string, error) {
 	var inst *Instance
 	var exists bool
-	if inst, exists = connectionPool[endpoint]; !exists {
+	if inst, exists = getInstance(endpoint); !exists {
 		return nil, errNotRegistered
 	}
 	var err error
@@ -365,11 +394,22 @@ func getInt(data string) int {
 	return res
 }
 
+func getFloat(data string) float64 {
+	res, _ := strconv.ParseFloat(data, 64)
+	return res
+}
+
 func getBool(data string) bool {
 	res, _ := strconv.ParseBool(data)
 	return res
 }
 
+// isMySQLError reports whether err is the error of number returned by the MySQL server.
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
 func match(pattern, s string) bool {
 	matched, err := regexp.MatchString(pattern, s)
 	if err != nil {
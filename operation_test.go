@@ -1,10 +1,14 @@
 package msops
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestReadDataSet(t *testing.T) {
@@ -185,3 +189,16 @@ func TestGetGlobalStatus(t *testing.T) {
 		t.Error("Get badEndpoint global status should cause error")
 	}
 }
+
+func TestIsMySQLError(t *testing.T) {
+	err := fmt.Errorf("set read_only: %w", &mysql.MySQLError{Number: 1238, Message: "Variable is a read only variable"})
+	if !isMySQLError(err, 1238) || !isReadOnlyVariableError(err) {
+		t.Error("Test isMySQLError failed: the wrapped error 1238 should be matched")
+	}
+	if isMySQLError(err, 1045) || isAccessDeniedError(err) {
+		t.Error("Test isMySQLError failed: error 1238 should not be matched as 1045")
+	}
+	if isMySQLError(errors.New("Error 1238: Variable is a read only variable"), 1238) || isMySQLError(nil, 1238) {
+		t.Error("Test isMySQLError failed: only the errors of the MySQL server should be matched")
+	}
+}
@@ -0,0 +1,133 @@
+package msops
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// PersistMode represents how SetPersistedVariable persists a variable to mysqld-auto.cnf.
+type PersistMode int
+
+const (
+	// Persist executes "SET PERSIST", which changes the runtime value as well.
+	Persist PersistMode = iota
+
+	// PersistOnly executes "SET PERSIST_ONLY", which takes effect after restart.
+	// It can be used for the non-dynamic variables.
+	PersistOnly
+)
+
+// PersistedVariableDrift represents a persisted variable whose runtime value differs from the persisted one.
+//
+// Source is 'VARIABLE_SOURCE' of performance_schema.variables_info, e.g. "PERSISTED" or "DYNAMIC".
+type PersistedVariableDrift struct {
+	Name      string
+	Persisted string
+	Runtime   string
+	Source    string
+}
+
+var (
+	errPersistUnsupported = errors.New("persisted variables are supported since MySQL 8.0.11")
+	errPersistModeInvalid = errors.New("the persist mode is not valid")
+)
+
+// SetPersistedVariable sets the variable with "SET PERSIST" or "SET PERSIST_ONLY" according to mode,
+// so that the change survives restarts. The value is validated as SetGlobalVariable does.
+func SetPersistedVariable(endpoint, key string, value interface{}, mode PersistMode) error {
+	var version serverVersion
+	var assignment variableAssignment
+	var err error
+	if mode != Persist && mode != PersistOnly {
+		return errPersistModeInvalid
+	}
+	if version, err = persistVersion(endpoint); err != nil {
+		return err
+	}
+	if assignment, err = prepareGlobalVariable(endpoint, key, value, version, mode == PersistOnly); err != nil {
+		return err
+	}
+	if mode == PersistOnly {
+		return execute(endpoint, fmt.Sprintf("SET PERSIST_ONLY %s=?", assignment.name), assignment.arg)
+	}
+	return execute(endpoint, fmt.Sprintf("SET PERSIST %s=?", assignment.name), assignment.arg)
+}
+
+// GetPersistedVariables reads performance_schema.persisted_variables of the endpoint.
+func GetPersistedVariables(endpoint string) (map[string]string, error) {
+	var dataSet []map[string]string
+	var err error
+	if _, err = persistVersion(endpoint); err != nil {
+		return nil, err
+	}
+	if dataSet, err = readDataSet(endpoint, "SELECT * FROM performance_schema.persisted_variables"); err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for _, row := range dataSet {
+		result[row["VARIABLE_NAME"]] = row["VARIABLE_VALUE"]
+	}
+	return result, nil
+}
+
+// ResetPersist removes the variable from mysqld-auto.cnf without changing its runtime value.
+// All the persisted variables are removed if key is empty.
+func ResetPersist(endpoint, key string) error {
+	if _, err := persistVersion(endpoint); err != nil {
+		return err
+	}
+	if key == "" {
+		return execute(endpoint, "RESET PERSIST")
+	}
+	if !globalKeyExp.MatchString(key) {
+		return errKeyInvalid
+	}
+	return execute(endpoint, "RESET PERSIST IF EXISTS "+key)
+}
+
+// GetPersistedVariableDrifts returns the persisted variables whose runtime values differ from the persisted ones,
+// sorted by the name. They're either changed by "SET GLOBAL" or persisted by "SET PERSIST_ONLY" after startup.
+func GetPersistedVariableDrifts(endpoint string) ([]PersistedVariableDrift, error) {
+	var persisted, runtime map[string]string
+	var dataSet []map[string]string
+	var err error
+	if persisted, err = GetPersistedVariables(endpoint); err != nil {
+		return nil, err
+	}
+	if runtime, err = GetGlobalVariables(endpoint, "%"); err != nil {
+		return nil, err
+	}
+	if dataSet, err = readDataSet(endpoint, "SELECT VARIABLE_NAME, VARIABLE_SOURCE FROM performance_schema.variables_info"); err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string)
+	for _, row := range dataSet {
+		sources[row["VARIABLE_NAME"]] = row["VARIABLE_SOURCE"]
+	}
+
+	names := make([]string, 0, len(persisted))
+	for name := range persisted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var drifts []PersistedVariableDrift
+	for _, name := range names {
+		if current, exists := runtime[name]; !exists || !variableValuesEqual(current, persisted[name]) {
+			drifts = append(drifts, PersistedVariableDrift{Name: name, Persisted: persisted[name], Runtime: current, Source: sources[name]})
+		}
+	}
+	return drifts, nil
+}
+
+// persistVersion returns the version of the endpoint, or errPersistUnsupported before MySQL 8.0.11.
+func persistVersion(endpoint string) (serverVersion, error) {
+	version, err := getVersion(endpoint)
+	if err != nil {
+		return version, err
+	}
+	if !version.atLeast(8, 0, 11) {
+		return version, errPersistUnsupported
+	}
+	return version, nil
+}
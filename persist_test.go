@@ -0,0 +1,51 @@
+package msops
+
+import (
+	"testing"
+)
+
+func TestPersistedVariables(t *testing.T) {
+	if SetPersistedVariable(testEndpoint3, "max_connections", 500, PersistMode(-1)) != errPersistModeInvalid {
+		t.Error("Test SetPersistedVariable invalid mode error: should return errPersistModeInvalid")
+	}
+	if SetPersistedVariable(unregisteredEndpoint, "max_connections", 500, Persist) != errNotRegistered {
+		t.Error("Test SetPersistedVariable unregisteredEndpoint error: should return errNotRegistered")
+	}
+	version, err := getVersion(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test getVersion error: %s", err.Error())
+	}
+	if !version.atLeast(8, 0, 11) {
+		if SetPersistedVariable(testEndpoint3, "max_connections", 500, Persist) != errPersistUnsupported {
+			t.Error("Test SetPersistedVariable before 8.0.11 error: should return errPersistUnsupported")
+		}
+		if _, err = GetPersistedVariables(testEndpoint3); err != errPersistUnsupported {
+			t.Error("Test GetPersistedVariables before 8.0.11 error: should return errPersistUnsupported")
+		}
+		if ResetPersist(testEndpoint3, "") != errPersistUnsupported {
+			t.Error("Test ResetPersist before 8.0.11 error: should return errPersistUnsupported")
+		}
+		return
+	}
+
+	defer ResetPersist(testEndpoint3, "")
+	if err = SetPersistedVariable(testEndpoint3, "innodb_log_file_size", "64M", PersistOnly); err != nil {
+		t.Errorf("Test SetPersistedVariable PERSIST_ONLY error: %s", err.Error())
+	}
+	if persisted, err := GetPersistedVariables(testEndpoint3); err != nil {
+		t.Errorf("Test GetPersistedVariables error: %s", err.Error())
+	} else if persisted["innodb_log_file_size"] != "67108864" {
+		t.Errorf("Test GetPersistedVariables failed: actual innodb_log_file_size %s, expected 67108864", persisted["innodb_log_file_size"])
+	}
+	if drifts, err := GetPersistedVariableDrifts(testEndpoint3); err != nil {
+		t.Errorf("Test GetPersistedVariableDrifts error: %s", err.Error())
+	} else if len(drifts) != 1 || drifts[0].Name != "innodb_log_file_size" {
+		t.Errorf("Test GetPersistedVariableDrifts failed: actual drifts %+v, expected innodb_log_file_size", drifts)
+	}
+	if err = ResetPersist(testEndpoint3, "innodb_log_file_size"); err != nil {
+		t.Errorf("Test ResetPersist error: %s", err.Error())
+	}
+	if ResetPersist(testEndpoint3, "a;b") != errKeyInvalid {
+		t.Error("Test ResetPersist with invalid key error: should return errKeyInvalid")
+	}
+}
@@ -0,0 +1,534 @@
+package msops
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statement represents one mutating statement executed at an endpoint.
+type Statement struct {
+	Endpoint string
+
+	// Query is the statement with placeholders replaced by args, and secrets such as passwords redacted.
+	Query string
+
+	// Template is the statement with placeholders, and Args are their values executed by ExecutePlan.
+	// The secrets in Args are saved as references to the registered credentials, such as the replPassword
+	// of an endpoint, and read from the registry by ExecutePlan.
+	Template string
+	Args     []interface{}
+
+	// Session is the id of the statements which must be executed on one connection, 0 if not required.
+	Session int
+}
+
+// Plan records the statements generated by mutating operations under dry-run mode.
+//
+// The statements in a plan can be reviewed, saved with encoding/json and then executed by ExecutePlan.
+// A plan with the passwords given by the caller, e.g. by CreateUser, can't be saved.
+type Plan struct {
+	Statements []Statement
+
+	mutex sync.Mutex
+
+	// endpoints are the endpoints recorded by the plan, nil for all the endpoints.
+	endpoints map[string]bool
+}
+
+// String returns the statements of the plan, one per line, in the form "endpoint: query".
+func (p *Plan) String() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var buf bytes.Buffer
+	for _, stmt := range p.Statements {
+		fmt.Fprintf(&buf, "%s: %s\n", stmt.Endpoint, stmt.Query)
+	}
+	return buf.String()
+}
+
+// Stop disables the dry-run mode of the plan. The recorded statements are kept.
+func (p *Plan) Stop() {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if globalPlan == p {
+		globalPlan = nil
+	}
+	for endpoint, plan := range endpointPlans {
+		if plan == p {
+			delete(endpointPlans, endpoint)
+		}
+	}
+}
+
+func (p *Plan) add(stmts ...Statement) {
+	p.mutex.Lock()
+	p.Statements = append(p.Statements, stmts...)
+	p.mutex.Unlock()
+}
+
+// planArg is the JSON form of an arg of Statement, keeping its type for ExecutePlan.
+type planArg struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+
+	// Ref and Endpoint locate the registered credential of a secret.
+	Ref      string `json:"ref,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// MarshalJSON encodes the args with their types, since numeric variables reject strings.
+func (stmt Statement) MarshalJSON() ([]byte, error) {
+	type statement Statement
+	encoded := struct {
+		statement
+		Args []planArg
+	}{statement: statement(stmt)}
+	for _, arg := range stmt.Args {
+		var a planArg
+		switch v := arg.(type) {
+		case nil:
+			a.Type = "null"
+		case secret:
+			if v.ref == "" {
+				return nil, errSecretNotSaved
+			}
+			a = planArg{Type: "secret", Ref: v.ref, Endpoint: v.endpoint}
+		case []byte:
+			a = planArg{Type: "string", Value: string(v)}
+		default:
+			value := reflect.ValueOf(arg)
+			switch value.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				a = planArg{Type: "int", Value: strconv.FormatInt(value.Int(), 10)}
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				a = planArg{Type: "uint", Value: strconv.FormatUint(value.Uint(), 10)}
+			case reflect.Float32, reflect.Float64:
+				a = planArg{Type: "float", Value: strconv.FormatFloat(value.Float(), 'g', -1, 64)}
+			case reflect.Bool:
+				a = planArg{Type: "bool", Value: strconv.FormatBool(value.Bool())}
+			default:
+				a = planArg{Type: "string", Value: fmt.Sprint(arg)}
+			}
+		}
+		encoded.Args = append(encoded.Args, a)
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes the statement encoded by MarshalJSON.
+func (stmt *Statement) UnmarshalJSON(data []byte) error {
+	type statement Statement
+	var decoded struct {
+		statement
+		Args []planArg
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*stmt = Statement(decoded.statement)
+	stmt.Args = nil
+	for _, a := range decoded.Args {
+		var arg interface{}
+		var err error
+		switch a.Type {
+		case "null":
+		case "secret":
+			arg = secret{ref: a.Ref, endpoint: a.Endpoint}
+		case "string":
+			arg = a.Value
+		case "int":
+			arg, err = strconv.ParseInt(a.Value, 10, 64)
+		case "uint":
+			arg, err = strconv.ParseUint(a.Value, 10, 64)
+		case "float":
+			arg, err = strconv.ParseFloat(a.Value, 64)
+		case "bool":
+			arg, err = strconv.ParseBool(a.Value)
+		default:
+			err = fmt.Errorf("unknown arg type %s", a.Type)
+		}
+		if err != nil {
+			return err
+		}
+		stmt.Args = append(stmt.Args, arg)
+	}
+	return nil
+}
+
+// secret wraps the args which should be redacted when rendering a statement.
+//
+// ref is the name of the registered credential of the endpoint which value is read from, e.g. "replPassword",
+// or empty if value is given by the caller.
+type secret struct {
+	value    string
+	ref      string
+	endpoint string
+}
+
+// replPasswordOf returns the registered replPassword of the endpoint as a secret.
+func replPasswordOf(endpoint string, inst *Instance) secret {
+	return secret{value: inst.replPassword, ref: "replPassword", endpoint: endpoint}
+}
+
+// resolve returns s with its value read from the registry if it refers to a registered credential.
+func (s secret) resolve() (secret, error) {
+	if s.ref == "" {
+		return s, nil
+	}
+	inst, exists := getInstance(s.endpoint)
+	if !exists {
+		return s, errNotRegistered
+	}
+	switch s.ref {
+	case "replPassword":
+		s.value = inst.replPassword
+	case "dbaPassword":
+		s.value = inst.dbaPassword
+	default:
+		return s, fmt.Errorf("unknown secret ref %s", s.ref)
+	}
+	return s, nil
+}
+
+const redacted = "'<redacted>'"
+
+// String and GoString keep the secrets out of the formatted plans and statements.
+func (s secret) String() string   { return redacted }
+func (s secret) GoString() string { return redacted }
+
+// sessionQuery is one of the statements executed by executeSession.
+type sessionQuery struct {
+	query string
+	args  []interface{}
+}
+
+var (
+	// dryRunMutex guards globalPlan, endpointPlans and lastSession.
+	dryRunMutex sync.Mutex
+
+	// globalPlan is the plan recording the statements of all the endpoints, nil if it's not started.
+	globalPlan *Plan
+
+	// endpointPlans are the plans started by StartDryRunFor, keyed by the endpoints.
+	endpointPlans = make(map[string]*Plan)
+
+	// lastSession is the id of the last statements executed by executeSession.
+	lastSession int
+
+	errDryRunActive   = errors.New("the endpoint is already under dry-run")
+	errSecretNotSaved = errors.New("the secret isn't a registered credential and can't be saved")
+)
+
+// StartDryRun enables dry-run mode for all the endpoints and returns a new empty plan.
+//
+// Under dry-run mode, ChangeMasterTo, ResetSlave, StartSlave, StopSlave, SetGlobalVariable, KillProcesses
+// and other mutating operations record the statements they would execute into the plan
+// instead of executing them. Read-only statements are still executed.
+//
+// The mode is process-wide, which turns the mutating operations of all the goroutines into no-ops.
+// Use StartDryRunFor to limit it to the endpoints being planned.
+//
+// errDryRunActive is returned if dry-run mode is already enabled for all or any of the endpoints.
+func StartDryRun() (*Plan, error) {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if globalPlan != nil || len(endpointPlans) > 0 {
+		return nil, errDryRunActive
+	}
+	globalPlan = &Plan{}
+	return globalPlan, nil
+}
+
+// StartDryRunFor enables dry-run mode only for the endpoints and returns a new empty plan,
+// so that the operations on the other endpoints are executed as usual. It's stopped by Plan.Stop.
+//
+// errDryRunActive is returned if any of the endpoints is already under dry-run.
+func StartDryRunFor(endpoints ...string) (*Plan, error) {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if globalPlan != nil {
+		return nil, errDryRunActive
+	}
+	plan := &Plan{endpoints: make(map[string]bool)}
+	for _, endpoint := range endpoints {
+		if _, exists := endpointPlans[endpoint]; exists {
+			return nil, errDryRunActive
+		}
+		plan.endpoints[endpoint] = true
+	}
+	for endpoint := range plan.endpoints {
+		endpointPlans[endpoint] = plan
+	}
+	return plan, nil
+}
+
+// StopDryRun disables the dry-run mode started by StartDryRun.
+func StopDryRun() {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	globalPlan = nil
+}
+
+// IsDryRun reports whether dry-run mode is enabled by StartDryRun.
+func IsDryRun() bool {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	return globalPlan != nil
+}
+
+// planOf returns the plan recording the statements of the endpoint, nil if it's not under dry-run.
+func planOf(endpoint string) *Plan {
+	dryRunMutex.Lock()
+	defer dryRunMutex.Unlock()
+	if globalPlan != nil {
+		return globalPlan
+	}
+	return endpointPlans[endpoint]
+}
+
+// isDryRun reports whether the endpoint is under dry-run.
+func isDryRun(endpoint string) bool {
+	return planOf(endpoint) != nil
+}
+
+// ExecutePlan executes the statements of a previously reviewed plan in order, regardless of dry-run mode.
+// The secrets are read from the registry before executing any statement.
+//
+// It stops at the first failed statement and returns its error.
+func ExecutePlan(plan *Plan) error {
+	return executePlan(plan, nil)
+}
+
+// ExecutePlanWithTags executes the plan as ExecutePlan does, attaching tags instead of the ones set by SetAuditTags
+// to the audit entries, so that the concurrent operations of different operators are audited with their own tags.
+func ExecutePlanWithTags(plan *Plan, tags AuditTags) error {
+	return executePlan(plan, &tags)
+}
+
+func executePlan(plan *Plan, tags *AuditTags) error {
+	plan.mutex.Lock()
+	stmts := append([]Statement(nil), plan.Statements...)
+	plan.mutex.Unlock()
+	for i := range stmts {
+		args := make([]interface{}, len(stmts[i].Args))
+		for j, arg := range stmts[i].Args {
+			if s, ok := arg.(secret); ok {
+				var err error
+				if arg, err = s.resolve(); err != nil {
+					return fmt.Errorf("statement %d (%s: %s) failed: %s", i, stmts[i].Endpoint, stmts[i].Query, err.Error())
+				}
+			}
+			args[j] = arg
+		}
+		stmts[i].Args = args
+	}
+	for i := 0; i < len(stmts); {
+		stmt := stmts[i]
+		j := i + 1
+		var err error
+		if stmt.Session == 0 {
+			err = runStatement(stmt, tags)
+		} else {
+			for j < len(stmts) && stmts[j].Session == stmt.Session {
+				j++
+			}
+			err = runSession(stmts[i:j], tags)
+		}
+		if err != nil {
+			return fmt.Errorf("statements %d-%d (%s: %s) failed: %s", i, j-1, stmt.Endpoint, stmt.Query, err.Error())
+		}
+		i = j
+	}
+	return nil
+}
+
+// execute executes the mutating statement query with placeholders replaced by args at the endpoint,
+// or records it into the plan under dry-run mode.
+func execute(endpoint, query string, args ...interface{}) error {
+	if _, exists := getInstance(endpoint); !exists {
+		return errNotRegistered
+	}
+	stmt := Statement{
+		Endpoint: endpoint,
+		Query:    renderStatement(query, args),
+		Template: query,
+		Args:     args,
+	}
+	if plan := planOf(endpoint); plan != nil {
+		plan.add(stmt)
+		return nil
+	}
+	return runStatement(stmt, nil)
+}
+
+// executeSession executes the statements in order on one dedicated connection of the endpoint,
+// which is required by session variables such as GTID_NEXT, or records them into the plan under dry-run mode.
+// The last query should restore the session variables, since the connection is reused afterwards.
+func executeSession(endpoint string, queries ...sessionQuery) error {
+	if _, exists := getInstance(endpoint); !exists {
+		return errNotRegistered
+	}
+	dryRunMutex.Lock()
+	lastSession++
+	session := lastSession
+	dryRunMutex.Unlock()
+	stmts := make([]Statement, 0, len(queries))
+	for _, q := range queries {
+		stmts = append(stmts, Statement{
+			Endpoint: endpoint,
+			Query:    renderStatement(q.query, q.args),
+			Template: q.query,
+			Args:     q.args,
+			Session:  session,
+		})
+	}
+	if plan := planOf(endpoint); plan != nil {
+		plan.add(stmts...)
+		return nil
+	}
+	return runSession(stmts, nil)
+}
+
+// runStatement executes stmt at its endpoint. tags are attached to the audit entry if not nil.
+func runStatement(stmt Statement, tags *AuditTags) error {
+	inst, exists := getInstance(stmt.Endpoint)
+	if !exists {
+		return errNotRegistered
+	}
+	return runStatementOn(inst.connection, stmt, tags)
+}
+
+// runSession executes the statements on one connection of their endpoint, so that the session variables
+// set by a statement are visible to the following ones.
+//
+// The connection is returned to the pool of the endpoint afterwards. If a statement fails, the open transaction
+// is rolled back and the last statement, which restores the session variables, is still executed.
+func runSession(stmts []Statement, tags *AuditTags) error {
+	inst, exists := getInstance(stmts[0].Endpoint)
+	if !exists {
+		return errNotRegistered
+	}
+	ctx := context.Background()
+	conn, err := inst.connection.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for i, stmt := range stmts {
+		if err = runStatementOn(conn, stmt, tags); err != nil {
+			if i < len(stmts)-1 {
+				conn.ExecContext(ctx, "ROLLBACK")
+				runStatementOn(conn, stmts[len(stmts)-1], tags)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// execer is implemented by *sql.DB and *sql.Conn.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runStatementOn executes stmt with conn.
+func runStatementOn(conn execer, stmt Statement, tags *AuditTags) error {
+	var query bytes.Buffer
+	args := make([]interface{}, 0, len(stmt.Args))
+	last := 0
+	for argIdx, pos := range placeholders(stmt.Template) {
+		if argIdx >= len(stmt.Args) {
+			break
+		}
+		query.WriteString(stmt.Template[last:pos])
+		last = pos + 1
+		switch arg := stmt.Args[argIdx].(type) {
+		case secret:
+			query.WriteByte('?')
+			args = append(args, arg.value)
+		default:
+			query.WriteByte('?')
+			args = append(args, arg)
+		}
+	}
+	query.WriteString(stmt.Template[last:])
+	start := time.Now()
+	_, err := conn.ExecContext(context.Background(), query.String(), args...)
+	audit(stmt, tags, start, err)
+	return err
+}
+
+// renderStatement replaces the placeholders in query with args for reviewing.
+func renderStatement(query string, args []interface{}) string {
+	var buf bytes.Buffer
+	last := 0
+	for argIdx, pos := range placeholders(query) {
+		if argIdx >= len(args) {
+			break
+		}
+		buf.WriteString(query[last:pos])
+		last = pos + 1
+		switch arg := args[argIdx].(type) {
+		case secret:
+			buf.WriteString(redacted)
+		case string:
+			buf.WriteString(quoteString(arg))
+		case nil:
+			buf.WriteString("NULL")
+		case bool:
+			if arg {
+				buf.WriteString("1")
+			} else {
+				buf.WriteString("0")
+			}
+		default:
+			fmt.Fprintf(&buf, "%v", arg)
+		}
+	}
+	buf.WriteString(query[last:])
+	return buf.String()
+}
+
+// placeholders returns the positions of the "?" placeholders in query, skipping the ones inside
+// the quoted string literals and identifiers, e.g. an account 'user?'@'%' inlined into the query.
+func placeholders(query string) []int {
+	var positions []int
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			quote = c
+		case quote == 0 && c == '?':
+			positions = append(positions, i)
+		case quote != 0 && quote != '`' && c == '\\':
+			// Skip the escaped character, e.g. \' inside the literal.
+			i++
+		case c == quote:
+			// A doubled quote inside the literal is read as closing and opening it again.
+			quote = 0
+		}
+	}
+	return positions
+}
+
+// quoteString quotes s as a string literal. The quotes are doubled instead of escaped by backslashes,
+// so that the literal can't be closed early when NO_BACKSLASH_ESCAPES is in sql_mode.
+// The backslashes in s are still doubled, which keeps s unchanged only without NO_BACKSLASH_ESCAPES.
+func quoteString(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", "''", -1) + "'"
+}
+
+// quoteIdentifier quotes name as an identifier with backticks.
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
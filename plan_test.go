@@ -0,0 +1,155 @@
+package msops
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenderStatement(t *testing.T) {
+	actual := renderStatement("CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?",
+		[]interface{}{"127.0.0.1", 3301, "it's", secret{value: "repl"}})
+	expected := `CHANGE MASTER TO MASTER_HOST='127.0.0.1', MASTER_PORT=3301, MASTER_USER='it''s', MASTER_PASSWORD='<redacted>'`
+	if actual != expected {
+		t.Errorf("Test renderStatement failed: actual %s, expected %s", actual, expected)
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	// The quote after a backslash mustn't close the literal with or without NO_BACKSLASH_ESCAPES.
+	if actual := quoteString(`a\' OR '1`); actual != `'a\\'' OR ''1'` {
+		t.Errorf("Test quoteString failed: actual %s, expected 'a\\\\'' OR ''1'", actual)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	query := "CREATE USER 'it\\'s?'@`h?`, \"a?\" IDENTIFIED BY ?"
+	if positions := placeholders(query); len(positions) != 1 || positions[0] != len(query)-1 {
+		t.Errorf("Test placeholders failed: actual %v, expected [%d]", positions, len(query)-1)
+	}
+	actual := renderStatement("CREATE USER 'a?b'@'%' IDENTIFIED BY ?", []interface{}{secret{value: "pass?"}})
+	expected := "CREATE USER 'a?b'@'%' IDENTIFIED BY '<redacted>'"
+	if actual != expected {
+		t.Errorf("Test renderStatement with quoted placeholder failed: actual %s, expected %s", actual, expected)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	plan, err := StartDryRun()
+	if err != nil {
+		t.Fatalf("Test StartDryRun error: %s", err.Error())
+	}
+	if !IsDryRun() {
+		t.Error("Test StartDryRun failed: dry-run mode should be enabled")
+	}
+	if _, err = StartDryRun(); err != errDryRunActive {
+		t.Error("Test StartDryRun again error: should return errDryRunActive")
+	}
+	if err := ChangeMasterTo(testEndpoint3, testEndpoint1, false); err != nil {
+		t.Errorf("Test DryRun ChangeMasterTo error: %s", err.Error())
+	}
+	if err := StartSlave(testEndpoint3); err != nil {
+		t.Errorf("Test DryRun StartSlave error: %s", err.Error())
+	}
+	if StopSlave(unregisteredEndpoint) != errNotRegistered {
+		t.Error("Test DryRun StopSlave unregisteredEndpoint error: should return errNotRegistered")
+	}
+	StopDryRun()
+
+	if len(plan.Statements) != 2 {
+		t.Fatalf("Test DryRun failed: actual %d statements, expected 2", len(plan.Statements))
+	}
+	if strings.Contains(plan.String(), testReplPass+"'") {
+		t.Errorf("Test DryRun failed: password is not redacted in %s", plan.String())
+	}
+	if slaveSt, err := GetSlaveStatus(testEndpoint3); err != nil {
+		t.Errorf("Test DryRun GetSlaveStatus error: %s", err.Error())
+	} else if slaveSt.MasterHost != "" {
+		t.Errorf("Test DryRun failed: CHANGE MASTER should not be executed, actual master host %s", slaveSt.MasterHost)
+	}
+
+	if err := ExecutePlan(plan); err != nil {
+		t.Errorf("Test ExecutePlan error: %s", err.Error())
+	} else if slaveSt, err := GetSlaveStatus(testEndpoint3); err != nil {
+		t.Errorf("Test ExecutePlan GetSlaveStatus error: %s", err.Error())
+	} else if slaveSt.MasterPort != 3301 {
+		t.Errorf("Test ExecutePlan failed: actual master port %d, expected 3301", slaveSt.MasterPort)
+	}
+	StopSlave(testEndpoint3)
+	ResetSlave(testEndpoint3, true)
+}
+
+func TestPlanJSON(t *testing.T) {
+	plan := &Plan{Statements: []Statement{
+		{Endpoint: testEndpoint3, Query: "SET GLOBAL long_query_time=1.5", Template: "SET GLOBAL long_query_time=?", Args: []interface{}{1.5}},
+		{Endpoint: testEndpoint3, Template: "CHANGE MASTER TO MASTER_PORT=?, MASTER_PASSWORD=?, MASTER_BIND=?",
+			Args: []interface{}{3301, replPasswordOf(testEndpoint1, &Instance{replPassword: testReplPass}), nil}, Session: 1},
+	}}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Test Plan MarshalJSON error: %s", err.Error())
+	}
+	if strings.Contains(string(data), `"value":"`+testReplPass+`"`) {
+		t.Errorf("Test Plan MarshalJSON failed: password is saved in %s", data)
+	}
+	var loaded Plan
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Test Plan UnmarshalJSON error: %s", err.Error())
+	}
+	expected := [][]interface{}{{1.5}, {int64(3301), secret{ref: "replPassword", endpoint: testEndpoint1}, nil}}
+	for i, stmt := range loaded.Statements {
+		if stmt.Template != plan.Statements[i].Template || stmt.Session != plan.Statements[i].Session ||
+			!reflect.DeepEqual(stmt.Args, expected[i]) {
+			t.Errorf("Test Plan JSON failed: actual %#v, expected args %#v", stmt, expected[i])
+		}
+	}
+	if formatted := fmt.Sprintf("%v %#v", plan.Statements[1].Args, plan.Statements[1].Args); strings.Contains(formatted, testReplPass) {
+		t.Errorf("Test secret formatting failed: secret is printed in %s", formatted)
+	}
+	if s, err := loaded.Statements[1].Args[1].(secret).resolve(); err != nil {
+		t.Errorf("Test secret resolve error: %s", err.Error())
+	} else if s.value != testReplPass {
+		t.Error("Test secret resolve failed: the registered replPassword is expected")
+	}
+
+	plan.Statements[1].Args[1] = secret{value: "pass"}
+	if _, err = json.Marshal(plan); err == nil || !strings.Contains(err.Error(), errSecretNotSaved.Error()) {
+		t.Error("Test Plan MarshalJSON caller secret error: should return errSecretNotSaved")
+	}
+}
+
+func TestStartDryRunFor(t *testing.T) {
+	plan, err := StartDryRunFor(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test StartDryRunFor error: %s", err.Error())
+	}
+	defer plan.Stop()
+	if _, err = StartDryRunFor(testEndpoint2, testEndpoint3); err != errDryRunActive {
+		t.Error("Test StartDryRunFor overlapped endpoints error: should return errDryRunActive")
+	}
+	if _, err = StartDryRun(); err != errDryRunActive {
+		t.Error("Test StartDryRun under StartDryRunFor error: should return errDryRunActive")
+	}
+	if IsDryRun() || !isDryRun(testEndpoint3) || isDryRun(testEndpoint2) {
+		t.Error("Test StartDryRunFor failed: only testEndpoint3 should be under dry-run")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			StopSlave(testEndpoint3)
+		}()
+	}
+	wg.Wait()
+	if len(plan.Statements) != 10 {
+		t.Errorf("Test StartDryRunFor concurrent recording failed: actual %d statements, expected 10", len(plan.Statements))
+	}
+	plan.Stop()
+	if isDryRun(testEndpoint3) {
+		t.Error("Test Plan.Stop failed: testEndpoint3 should not be under dry-run")
+	}
+}
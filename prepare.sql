@@ -1,9 +1,13 @@
 CREATE USER 'dba'@'%' IDENTIFIED BY 'dba';
 GRANT RELOAD, PROCESS, SUPER, REPLICATION CLIENT, REPLICATION SLAVE ON *.* TO 'dba'@'%';
 CREATE USER 'repl'@'%' IDENTIFIED BY 'repl';
-GRANT PROCESS, REPLICATION SLAVE ON *.* TO 'dba'@'%';
+GRANT PROCESS, REPLICATION SLAVE ON *.* TO 'repl'@'%';
 CREATE DATABASE data_test;
 GRANT ALL ON data_test.* TO 'dba'@'%';
+GRANT SELECT ON performance_schema.* TO 'dba'@'%';
+GRANT INSERT, DELETE ON mysql.plugin TO 'dba'@'%';
+GRANT CREATE USER ON *.* TO 'dba'@'%' WITH GRANT OPTION;
+GRANT SELECT ON mysql.user TO 'dba'@'%';
 USE data_test;
 CREATE TABLE tbl_test (
     id int primary key,
@@ -0,0 +1,89 @@
+package msops
+
+// ProcessListSource represents the table GetFullProcessList reads from.
+type ProcessListSource int
+
+const (
+	// ProcessListInformationSchema reads information_schema.PROCESSLIST.
+	// TimeMs is only provided by Percona Server and MariaDB, otherwise it's converted from Time.
+	ProcessListInformationSchema ProcessListSource = iota
+
+	// ProcessListPerformanceSchema reads performance_schema.threads, which fills ThreadID as well.
+	// ThreadOSID and ConnectionType are only provided since MySQL 5.7,
+	// and TimeMs of running statements is read from performance_schema.events_statements_current.
+	ProcessListPerformanceSchema
+)
+
+const (
+	informationSchemaProcessListQuery = "SELECT p.*, trx.trx_state, trx.trx_started " +
+		"FROM information_schema.PROCESSLIST p " +
+		"LEFT JOIN information_schema.INNODB_TRX trx ON trx.trx_mysql_thread_id = p.ID"
+	performanceSchemaProcessListQuery = "SELECT t.*, s.TIMER_WAIT AS STATEMENT_TIMER_WAIT, trx.trx_state, trx.trx_started " +
+		"FROM performance_schema.threads t " +
+		"LEFT JOIN performance_schema.events_statements_current s ON s.THREAD_ID = t.THREAD_ID AND s.NESTING_EVENT_LEVEL = 0 " +
+		"LEFT JOIN information_schema.INNODB_TRX trx ON trx.trx_mysql_thread_id = t.PROCESSLIST_ID " +
+		"WHERE t.PROCESSLIST_ID IS NOT NULL"
+)
+
+// picosecondsPerMs is used to convert the timers of performance_schema.
+const picosecondsPerMs = 1000000000
+
+// GetFullProcessList reads the processlist from source and returns the resultset.
+//
+// Unlike GetProcessList, 'Info' is not truncated, and the transaction state is joined from
+// information_schema.INNODB_TRX.
+func GetFullProcessList(endpoint string, source ProcessListSource) ([]Process, error) {
+	var dataSet []map[string]string
+	var err error
+	query := informationSchemaProcessListQuery
+	if source == ProcessListPerformanceSchema {
+		query = performanceSchemaProcessListQuery
+	}
+	if dataSet, err = readDataSet(endpoint, query); err != nil {
+		return nil, err
+	}
+	processes := make([]Process, 0, len(dataSet))
+	for _, row := range dataSet {
+		var process Process
+		if source == ProcessListPerformanceSchema {
+			process = Process{
+				ID:             getInt(row["PROCESSLIST_ID"]),
+				User:           row["PROCESSLIST_USER"],
+				Host:           row["PROCESSLIST_HOST"],
+				DB:             row["PROCESSLIST_DB"],
+				Command:        row["PROCESSLIST_COMMAND"],
+				Time:           getInt(row["PROCESSLIST_TIME"]),
+				State:          row["PROCESSLIST_STATE"],
+				Info:           row["PROCESSLIST_INFO"],
+				ThreadID:       getInt(row["THREAD_ID"]),
+				ThreadOSID:     getInt(row["THREAD_OS_ID"]),
+				ConnectionType: row["CONNECTION_TYPE"],
+			}
+			if timerWait := row["STATEMENT_TIMER_WAIT"]; timerWait != "" && process.Command == "Query" {
+				process.TimeMs = getInt(timerWait) / picosecondsPerMs
+			} else {
+				process.TimeMs = process.Time * 1000
+			}
+		} else {
+			process = Process{
+				ID:      getInt(row["ID"]),
+				User:    row["USER"],
+				Host:    row["HOST"],
+				DB:      row["DB"],
+				Command: row["COMMAND"],
+				Time:    getInt(row["TIME"]),
+				State:   row["STATE"],
+				Info:    row["INFO"],
+			}
+			if timeMs, exists := row["TIME_MS"]; exists {
+				process.TimeMs = int(getFloat(timeMs))
+			} else {
+				process.TimeMs = process.Time * 1000
+			}
+		}
+		process.TrxState = row["trx_state"]
+		process.TrxStarted = row["trx_started"]
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
@@ -0,0 +1,26 @@
+package msops
+
+import "testing"
+
+func TestGetFullProcessList(t *testing.T) {
+	for _, source := range []ProcessListSource{ProcessListInformationSchema, ProcessListPerformanceSchema} {
+		if processes, err := GetFullProcessList(testEndpoint1, source); err != nil {
+			t.Errorf("Test GetFullProcessList from source %d error: %s", source, err.Error())
+		} else if len(processes) == 0 {
+			t.Errorf("Test GetFullProcessList from source %d failed: processlist is empty", source)
+		} else {
+			var found bool
+			for _, process := range processes {
+				if process.User == testDBAUser && process.Command == "Query" {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Test GetFullProcessList from source %d failed: own query is not found", source)
+			}
+		}
+		if _, err := GetFullProcessList(badEndpoint, source); err == nil {
+			t.Errorf("Get badEndpoint full processlist from source %d should cause error", source)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package msops
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// QueryKillRule describes the long-running queries to be killed by QueryKiller.
+type QueryKillRule struct {
+	Name string
+
+	// Filter matches the processes to be killed, e.g. Filter.MinTime is the max time of queries allowed.
+	Filter KillFilter
+
+	// MinThreadsRunning enables the rule only when 'Threads_running' of the instance exceeds it.
+	MinThreadsRunning int
+}
+
+// QueryKillAction represents the action QueryKiller took on one process.
+type QueryKillAction int
+
+const (
+	// QueryKillKilled implies that the process is killed.
+	QueryKillKilled QueryKillAction = iota
+
+	// QueryKillLogged implies that the process matched a rule but is not killed under log-only mode.
+	QueryKillLogged
+
+	// QueryKillRateLimited implies that the process matched a rule
+	// but is not killed because the kill limit of the interval is reached.
+	QueryKillRateLimited
+
+	// QueryKillFailed implies that killing the process or reading the processlist failed.
+	QueryKillFailed
+)
+
+// QueryKillEvent is published by QueryKiller for each action.
+type QueryKillEvent struct {
+	Time     time.Time
+	Endpoint string
+	Rule     string
+	Process  Process
+	Action   QueryKillAction
+	Error    error
+}
+
+// QueryKillerOptions configures QueryKiller.
+type QueryKillerOptions struct {
+	// Endpoints are the instances to be protected. All the registered instances are used if it's empty.
+	Endpoints []string
+
+	Interval time.Duration
+	Rules    []QueryKillRule
+
+	// LogOnly publishes QueryKillLogged events instead of killing the processes.
+	LogOnly bool
+
+	// MaxKillsPerInterval limits the kills of each instance in one interval. 0 means no limit.
+	MaxKillsPerInterval int
+
+	// OnEvent is called for each action. It's called from the goroutines of the instances concurrently.
+	OnEvent func(QueryKillEvent)
+}
+
+// QueryKiller kills the long-running queries matching the rules on an interval per instance.
+type QueryKiller struct {
+	opts QueryKillerOptions
+
+	// mutex guards stop, which is nil if the killer is not running.
+	mutex sync.Mutex
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+var (
+	errIntervalInvalid = errors.New("interval must be positive")
+	errNoRules         = errors.New("no rules are specified")
+)
+
+// NewQueryKiller creates a QueryKiller, returning an error if the options are invalid.
+func NewQueryKiller(opts QueryKillerOptions) (*QueryKiller, error) {
+	if opts.Interval <= 0 {
+		return nil, errIntervalInvalid
+	}
+	if len(opts.Rules) == 0 {
+		return nil, errNoRules
+	}
+	for _, rule := range opts.Rules {
+		if err := rule.Filter.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, endpoint := range opts.Endpoints {
+		if _, exists := getInstance(endpoint); !exists {
+			return nil, errNotRegistered
+		}
+	}
+	return &QueryKiller{opts: opts}, nil
+}
+
+// Start starts one goroutine per instance checking the processes on the interval.
+// It does nothing if the killer is already running. A stopped killer can be started again.
+func (k *QueryKiller) Start() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.stop != nil {
+		return
+	}
+	endpoints := k.opts.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = registeredEndpoints()
+	}
+	k.stop = make(chan struct{})
+	for _, endpoint := range endpoints {
+		k.wg.Add(1)
+		go k.run(endpoint, k.stop)
+	}
+}
+
+// Stop stops all the goroutines and waits for them to exit. It does nothing if the killer is not running.
+func (k *QueryKiller) Stop() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.stop == nil {
+		return
+	}
+	close(k.stop)
+	k.stop = nil
+	k.wg.Wait()
+}
+
+func (k *QueryKiller) run(endpoint string, stop chan struct{}) {
+	defer k.wg.Done()
+	ticker := time.NewTicker(k.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			k.check(endpoint)
+		}
+	}
+}
+
+// check applies the rules to the processes of the endpoint once.
+func (k *QueryKiller) check(endpoint string) {
+	inst, exists := getInstance(endpoint)
+	if !exists {
+		k.publish(QueryKillEvent{Endpoint: endpoint, Action: QueryKillFailed, Error: errNotRegistered})
+		return
+	}
+	threadsRunning := -1
+	for _, rule := range k.opts.Rules {
+		if rule.MinThreadsRunning > 0 {
+			status, err := GetGlobalStatus(endpoint, "Threads_running")
+			if err != nil {
+				k.publish(QueryKillEvent{Endpoint: endpoint, Action: QueryKillFailed, Error: err})
+				return
+			}
+			threadsRunning = getInt(status["Threads_running"])
+			break
+		}
+	}
+	processes, selfID, err := readKillableProcesses(endpoint)
+	if err != nil {
+		k.publish(QueryKillEvent{Endpoint: endpoint, Action: QueryKillFailed, Error: err})
+		return
+	}
+	kills := 0
+	for _, process := range processes {
+		for _, rule := range k.opts.Rules {
+			if rule.MinThreadsRunning > 0 && threadsRunning <= rule.MinThreadsRunning {
+				continue
+			}
+			if !rule.Filter.matches(process, selfID, inst.dbaUser) {
+				continue
+			}
+			event := QueryKillEvent{Endpoint: endpoint, Rule: rule.Name, Process: process}
+			switch {
+			case k.opts.LogOnly:
+				event.Action = QueryKillLogged
+			case k.opts.MaxKillsPerInterval > 0 && kills >= k.opts.MaxKillsPerInterval:
+				event.Action = QueryKillRateLimited
+			default:
+				kills++
+				if event.Error = KillProcess(endpoint, process.ID, rule.Filter.KillQuery); event.Error != nil {
+					event.Action = QueryKillFailed
+				} else {
+					event.Action = QueryKillKilled
+				}
+			}
+			k.publish(event)
+			break
+		}
+	}
+}
+
+func (k *QueryKiller) publish(event QueryKillEvent) {
+	if k.opts.OnEvent == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	k.opts.OnEvent(event)
+}
@@ -0,0 +1,83 @@
+package msops
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewQueryKiller(t *testing.T) {
+	rules := []QueryKillRule{{Name: "long select", Filter: KillFilter{Commands: []string{"Query"}, MinTime: 60}}}
+	if _, err := NewQueryKiller(QueryKillerOptions{Rules: rules}); err != errIntervalInvalid {
+		t.Error("Test NewQueryKiller without interval error: should return errIntervalInvalid")
+	}
+	if _, err := NewQueryKiller(QueryKillerOptions{Interval: time.Second}); err != errNoRules {
+		t.Error("Test NewQueryKiller without rules error: should return errNoRules")
+	}
+	if _, err := NewQueryKiller(QueryKillerOptions{Interval: time.Second, Rules: rules, Endpoints: []string{unregisteredEndpoint}}); err != errNotRegistered {
+		t.Error("Test NewQueryKiller unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := NewQueryKiller(QueryKillerOptions{Interval: time.Second, Rules: rules}); err != nil {
+		t.Errorf("Test NewQueryKiller error: %s", err.Error())
+	}
+}
+
+func TestQueryKiller(t *testing.T) {
+	var mu sync.Mutex
+	var events []QueryKillEvent
+	killer, err := NewQueryKiller(QueryKillerOptions{
+		Endpoints: []string{testEndpoint1, badEndpoint},
+		Interval:  10 * time.Millisecond,
+		Rules: []QueryKillRule{{
+			Name:   "everything",
+			Filter: KillFilter{IncludeSystem: true, IncludeSelf: true},
+		}},
+		LogOnly: true,
+		OnEvent: func(event QueryKillEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Test NewQueryKiller error: %s", err.Error())
+	}
+	killer.Start()
+	time.Sleep(100 * time.Millisecond)
+	killer.Stop()
+
+	var logged, failed int
+	mu.Lock()
+	defer mu.Unlock()
+	for _, event := range events {
+		switch {
+		case event.Endpoint == testEndpoint1 && event.Action == QueryKillLogged:
+			logged++
+		case event.Endpoint == badEndpoint && event.Action == QueryKillFailed:
+			failed++
+		}
+	}
+	if logged == 0 || failed == 0 {
+		t.Errorf("Test QueryKiller failed: actual %d logged and %d failed events, expected both positive", logged, failed)
+	}
+	if CheckInstance(testEndpoint1) != InstanceOK {
+		t.Error("Test QueryKiller failed: processes should not be killed under log-only mode")
+	}
+}
+
+func TestQueryKillerStartStop(t *testing.T) {
+	killer, err := NewQueryKiller(QueryKillerOptions{
+		Interval: time.Hour,
+		Rules:    []QueryKillRule{{Name: "long select", Filter: KillFilter{Commands: []string{"Query"}, MinTime: 60}}},
+	})
+	if err != nil {
+		t.Fatalf("Test NewQueryKiller error: %s", err.Error())
+	}
+	killer.Stop()
+	killer.Start()
+	killer.Start()
+	killer.Stop()
+	killer.Stop()
+	killer.Start()
+	killer.Stop()
+}
@@ -0,0 +1,164 @@
+package msops
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ReadOnlyMode represents how an instance is fenced from writes.
+type ReadOnlyMode int
+
+const (
+	// ReadOnly turns on read_only, which still allows the users with SUPER privilege to write.
+	ReadOnly ReadOnlyMode = iota
+
+	// SuperReadOnly turns on both read_only and super_read_only, which rejects the writes of all the users
+	// except the replication threads. It's supported since MySQL 5.7.8.
+	SuperReadOnly
+)
+
+// FenceOptions describes how SetReadOnlyWithOptions deals with the transactions having written
+// before read_only is turned on, which fail when committing.
+type FenceOptions struct {
+	// WaitTimeout is how long to wait for the write transactions to finish. They're not waited if it's 0.
+	WaitTimeout time.Duration
+
+	// KillWriters kills the connections of the write transactions remaining after WaitTimeout.
+	// Otherwise an error is returned if there're remaining ones.
+	KillWriters bool
+}
+
+// fencePollInterval is the interval of checking the write transactions.
+var fencePollInterval = 100 * time.Millisecond
+
+var (
+	errReadOnlyModeInvalid      = errors.New("the read-only mode is not valid")
+	errSuperReadOnlyUnsupported = errors.New("super_read_only is supported since MySQL 5.7.8")
+	errReplicaRunning           = errors.New("the instance is a replica with running threads")
+)
+
+// SetReadOnly fences the endpoint from writes with mode, and verifies that the variables take effect.
+func SetReadOnly(endpoint string, mode ReadOnlyMode) error {
+	return SetReadOnlyWithOptions(endpoint, mode, FenceOptions{})
+}
+
+// SetReadOnlyWithOptions is the same as SetReadOnly,
+// but waits for or kills the write transactions open at the endpoint according to opts afterwards.
+//
+// read_only is turned on before super_read_only, which is turned off under ReadOnly mode.
+func SetReadOnlyWithOptions(endpoint string, mode ReadOnlyMode, opts FenceOptions) error {
+	var version serverVersion
+	var err error
+	if mode != ReadOnly && mode != SuperReadOnly {
+		return errReadOnlyModeInvalid
+	}
+	if version, err = getVersion(endpoint); err != nil {
+		return err
+	}
+	if mode == SuperReadOnly && !version.atLeast(5, 7, 8) {
+		return errSuperReadOnlyUnsupported
+	}
+	if err = SetGlobalVariable(endpoint, "read_only", "ON"); err != nil {
+		return err
+	}
+	if mode == SuperReadOnly {
+		if err = SetGlobalVariable(endpoint, "super_read_only", "ON"); err != nil {
+			return err
+		}
+	} else if version.atLeast(5, 7, 8) {
+		if err = SetGlobalVariable(endpoint, "super_read_only", "OFF"); err != nil {
+			return err
+		}
+	}
+	if err = verifyReadOnly(endpoint, true, mode == SuperReadOnly); err != nil {
+		return err
+	}
+	return fenceWriters(endpoint, opts)
+}
+
+// SetWritable turns off super_read_only and read_only of the endpoint in order, and verifies that they take effect.
+//
+// It refuses to make a replica with running I/O or SQL thread writable unless force is true,
+// since the writes will conflict with the replicated ones.
+func SetWritable(endpoint string, force bool) error {
+	var variables map[string]string
+	var slaveStatus SlaveStatus
+	var err error
+	if slaveStatus, err = GetSlaveStatus(endpoint); err != nil {
+		return err
+	}
+	if !force && !reflect.DeepEqual(emptySlaveStatus, slaveStatus) &&
+		(slaveStatus.SlaveIORunning == "Yes" || slaveStatus.SlaveSQLRunning == "Yes") {
+		return errReplicaRunning
+	}
+	if variables, err = GetGlobalVariables(endpoint, "super_read_only"); err != nil {
+		return err
+	}
+	if _, exists := variables["super_read_only"]; exists {
+		if err = SetGlobalVariable(endpoint, "super_read_only", "OFF"); err != nil {
+			return err
+		}
+	}
+	if err = SetGlobalVariable(endpoint, "read_only", "OFF"); err != nil {
+		return err
+	}
+	return verifyReadOnly(endpoint, false, false)
+}
+
+// verifyReadOnly checks that read_only and super_read_only, if supported, are the expected values.
+// It's skipped under dry-run since nothing is executed.
+func verifyReadOnly(endpoint string, readOnly, superReadOnly bool) error {
+	if isDryRun(endpoint) {
+		return nil
+	}
+	variables, err := GetGlobalVariables(endpoint, "%read_only")
+	if err != nil {
+		return err
+	}
+	if getBool(onOffToBool(variables["read_only"])) != readOnly {
+		return fmt.Errorf("read_only is %s after setting", variables["read_only"])
+	}
+	if value, exists := variables["super_read_only"]; exists && getBool(onOffToBool(value)) != superReadOnly {
+		return fmt.Errorf("super_read_only is %s after setting", value)
+	}
+	return nil
+}
+
+// fenceWriters waits for the transactions having modified rows to finish, and kills the remaining ones
+// if opts.KillWriters is true. The replication threads are ignored, and nothing is checked if opts is zero.
+func fenceWriters(endpoint string, opts FenceOptions) error {
+	if isDryRun(endpoint) || opts == (FenceOptions{}) {
+		return nil
+	}
+	deadline := time.Now().Add(opts.WaitTimeout)
+	for {
+		transactions, err := GetOpenTransactions(endpoint)
+		if err != nil {
+			return err
+		}
+		var writers []Transaction
+		for _, trx := range transactions {
+			if trx.RowsModified > 0 && trx.Process.ID != 0 && trx.Process.User != "system user" {
+				writers = append(writers, trx)
+			}
+		}
+		if len(writers) == 0 {
+			return nil
+		}
+		if time.Now().Before(deadline) {
+			time.Sleep(fencePollInterval)
+			continue
+		}
+		if !opts.KillWriters {
+			return fmt.Errorf("%d write transactions remain open", len(writers))
+		}
+		for _, trx := range writers {
+			if err = KillProcess(endpoint, trx.Process.ID, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
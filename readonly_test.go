@@ -0,0 +1,64 @@
+package msops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetReadOnly(t *testing.T) {
+	if SetReadOnly(testEndpoint3, ReadOnlyMode(-1)) != errReadOnlyModeInvalid {
+		t.Error("Test SetReadOnly invalid mode error: should return errReadOnlyModeInvalid")
+	}
+	if SetReadOnly(unregisteredEndpoint, ReadOnly) != errNotRegistered {
+		t.Error("Test SetReadOnly unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if SetReadOnly(badEndpoint, ReadOnly) == nil {
+		t.Error("Set badEndpoint read-only should cause error")
+	}
+
+	if err := SetWritable(testEndpoint3, false); err != nil {
+		t.Errorf("Test SetWritable error: %s", err.Error())
+	} else if variables, err := GetGlobalVariables(testEndpoint3, "read_only"); err != nil {
+		t.Errorf("Test SetWritable GetGlobalVariables error: %s", err.Error())
+	} else if variables["read_only"] != "OFF" {
+		t.Errorf("Test SetWritable failed: actual read_only %s, expected OFF", variables["read_only"])
+	}
+
+	opts := FenceOptions{WaitTimeout: time.Second, KillWriters: true}
+	if err := SetReadOnlyWithOptions(testEndpoint3, ReadOnly, opts); err != nil {
+		t.Errorf("Test SetReadOnlyWithOptions error: %s", err.Error())
+	} else if variables, err := GetGlobalVariables(testEndpoint3, "read_only"); err != nil {
+		t.Errorf("Test SetReadOnlyWithOptions GetGlobalVariables error: %s", err.Error())
+	} else if variables["read_only"] != "ON" {
+		t.Errorf("Test SetReadOnlyWithOptions failed: actual read_only %s, expected ON", variables["read_only"])
+	}
+
+	version, err := getVersion(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test getVersion error: %s", err.Error())
+	}
+	if err = SetReadOnly(testEndpoint3, SuperReadOnly); version.atLeast(5, 7, 8) && err != nil {
+		t.Errorf("Test SetReadOnly super_read_only error: %s", err.Error())
+	} else if !version.atLeast(5, 7, 8) && err != errSuperReadOnlyUnsupported {
+		t.Error("Test SetReadOnly super_read_only before 5.7.8 error: should return errSuperReadOnlyUnsupported")
+	}
+	SetReadOnly(testEndpoint3, ReadOnly)
+}
+
+func TestSetWritableReplica(t *testing.T) {
+	if err := ChangeMasterTo(testEndpoint3, testEndpoint1, false); err != nil {
+		t.Fatalf("Test SetWritable ChangeMasterTo error: %s", err.Error())
+	}
+	defer ResetSlave(testEndpoint3, true)
+	if err := StartSlave(testEndpoint3); err != nil {
+		t.Fatalf("Test SetWritable StartSlave error: %s", err.Error())
+	}
+	defer StopSlave(testEndpoint3)
+	if SetWritable(testEndpoint3, false) != errReplicaRunning {
+		t.Error("Test SetWritable running replica error: should return errReplicaRunning")
+	}
+	if err := SetWritable(testEndpoint3, true); err != nil {
+		t.Errorf("Test SetWritable forced error: %s", err.Error())
+	}
+	SetReadOnly(testEndpoint3, ReadOnly)
+}
@@ -0,0 +1,143 @@
+package msops
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DesiredVariables describes the desired global variables of an instance.
+type DesiredVariables struct {
+	Variables map[string]string
+
+	// Persist uses "SET PERSIST" on MySQL 8.0 so that the changes survive restarts.
+	Persist bool
+}
+
+// DesiredState describes the desired global variables of a cluster,
+// where the variables of a role such as "master" or "replica" override the common ones.
+type DesiredState struct {
+	Common  map[string]string
+	Roles   map[string]map[string]string
+	Persist bool
+}
+
+// VariableChange records the change of one variable made by Reconcile.
+type VariableChange struct {
+	Name string
+	From string
+	To   string
+
+	// Applied is false if the change failed or is recorded under dry-run.
+	Applied bool
+	Error   error
+}
+
+// ReconcileReport is the result of Reconcile.
+// Warnings records the variables skipped, e.g. the unknown or non-dynamic ones.
+type ReconcileReport struct {
+	Changes  []VariableChange
+	Warnings []string
+}
+
+// ForRole returns the desired variables of the role, merging the common ones.
+func (state DesiredState) ForRole(role string) DesiredVariables {
+	desired := DesiredVariables{Variables: make(map[string]string), Persist: state.Persist}
+	for name, value := range state.Common {
+		desired.Variables[normalizeVariableName(name)] = value
+	}
+	for name, value := range state.Roles[role] {
+		desired.Variables[normalizeVariableName(name)] = value
+	}
+	return desired
+}
+
+// Reconcile converges the global variables of the endpoint to desired, and returns the changes made.
+//
+// The unknown, read-only and non-dynamic variables are skipped with warnings.
+// The changes are verified by reading the variables again, which is skipped under dry-run.
+func Reconcile(endpoint string, desired DesiredVariables) (ReconcileReport, error) {
+	var report ReconcileReport
+	var version serverVersion
+	var current map[string]string
+	var err error
+	if version, err = getVersion(endpoint); err != nil {
+		return report, err
+	}
+	if current, err = GetGlobalVariables(endpoint, "%"); err != nil {
+		return report, err
+	}
+	persist := desired.Persist
+	if persist && !version.atLeast(8, 0, 11) {
+		report.addWarning("SET PERSIST is supported since MySQL 8.0.11, SET GLOBAL is used instead")
+		persist = false
+	}
+
+	names := make([]string, 0, len(desired.Variables))
+	values := make(map[string]string)
+	for name, value := range desired.Variables {
+		name = normalizeVariableName(name)
+		names = append(names, name)
+		values[name] = value
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, exists := current[name]
+		switch {
+		case !exists:
+			report.addWarning("%s is unknown, skipped", name)
+			continue
+		case variableValuesEqual(value, values[name]):
+			continue
+		case isStaticVariable(name, version):
+			report.addWarning("%s is not dynamic and requires restart, skipped", name)
+			continue
+		}
+		change := VariableChange{Name: name, From: value, To: values[name]}
+		if persist {
+			change.Error = SetPersistedVariable(endpoint, name, variableArg(values[name]), Persist)
+		} else {
+			change.Error = SetGlobalVariable(endpoint, name, variableArg(values[name]))
+		}
+		if isReadOnlyVariableError(change.Error) || change.Error == errVariableNotDynamic || change.Error == errVariableSession {
+			report.addWarning("%s is read-only, skipped", name)
+			continue
+		}
+		change.Applied = change.Error == nil && !isDryRun(endpoint)
+		report.Changes = append(report.Changes, change)
+	}
+
+	if isDryRun(endpoint) || len(report.Changes) == 0 {
+		return report, nil
+	}
+	if current, err = GetGlobalVariables(endpoint, "%"); err != nil {
+		return report, err
+	}
+	for i := range report.Changes {
+		change := &report.Changes[i]
+		if change.Applied && !variableValuesEqual(current[change.Name], change.To) {
+			change.Applied = false
+			change.Error = fmt.Errorf("%s is %s after setting", change.Name, current[change.Name])
+		}
+	}
+	return report, nil
+}
+
+func (report *ReconcileReport) addWarning(format string, args ...interface{}) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// variableArg converts the value to an integer if possible, since numeric variables reject strings.
+// The other values are Unvalidated, so that the desired string variables not in the catalog are set as well.
+func variableArg(value string) interface{} {
+	if n, err := strconv.ParseInt(normalizeVariable(value), 10, 64); err == nil {
+		return n
+	}
+	return Unvalidated(value)
+}
+
+// isReadOnlyVariableError reports whether err is ER_INCORRECT_GLOBAL_LOCAL_VAR,
+// which is returned when setting a read-only variable.
+func isReadOnlyVariableError(err error) bool {
+	return isMySQLError(err, 1238)
+}
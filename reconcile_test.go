@@ -0,0 +1,77 @@
+package msops
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDesiredStateForRole(t *testing.T) {
+	state := DesiredState{
+		Common: map[string]string{"binlog-format": "ROW", "read_only": "ON"},
+		Roles:  map[string]map[string]string{"master": {"read_only": "OFF"}},
+	}
+	expected := map[string]string{"binlog_format": "ROW", "read_only": "OFF"}
+	if desired := state.ForRole("master"); !reflect.DeepEqual(desired.Variables, expected) {
+		t.Errorf("Test DesiredState ForRole failed: actual %v, expected %v", desired.Variables, expected)
+	}
+	if desired := state.ForRole("replica"); desired.Variables["read_only"] != "ON" {
+		t.Errorf("Test DesiredState ForRole failed: actual read_only %s, expected ON", desired.Variables["read_only"])
+	}
+}
+
+func TestVariableArg(t *testing.T) {
+	if arg := variableArg("128M"); arg != int64(134217728) {
+		t.Errorf("Test variableArg failed: actual %v, expected 134217728", arg)
+	}
+	if arg := variableArg("ROW"); arg != Unvalidated("ROW") {
+		t.Errorf("Test variableArg failed: actual %v, expected ROW", arg)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	if _, err := Reconcile(unregisteredEndpoint, DesiredVariables{}); err != errNotRegistered {
+		t.Error("Test Reconcile unregisteredEndpoint error: should return errNotRegistered")
+	}
+	variables, err := GetGlobalVariables(testEndpoint3, "max_connections")
+	if err != nil {
+		t.Fatalf("Test Reconcile GetGlobalVariables error: %s", err.Error())
+	}
+	origin := variables["max_connections"]
+	defer SetGlobalVariable(testEndpoint3, "max_connections", getInt(origin))
+	target := strconv.Itoa(getInt(origin) + 10)
+	desired := DesiredVariables{Variables: map[string]string{
+		"max_connections":  target,
+		"port":             "3399",
+		"no_such_variable": "1",
+		"version":          "1.0",
+	}}
+
+	plan, err := StartDryRun()
+	if err != nil {
+		t.Fatalf("Test Reconcile StartDryRun error: %s", err.Error())
+	}
+	report, err := Reconcile(testEndpoint3, desired)
+	StopDryRun()
+	if err != nil {
+		t.Fatalf("Test Reconcile under dry-run error: %s", err.Error())
+	}
+	if len(plan.Statements) != 1 || len(report.Changes) != 1 || report.Changes[0].Applied {
+		t.Errorf("Test Reconcile under dry-run failed: actual changes %+v, statements %d", report.Changes, len(plan.Statements))
+	}
+
+	if report, err = Reconcile(testEndpoint3, desired); err != nil {
+		t.Fatalf("Test Reconcile error: %s", err.Error())
+	}
+	if len(report.Changes) != 1 || !report.Changes[0].Applied || report.Changes[0].Name != "max_connections" {
+		t.Errorf("Test Reconcile failed: actual changes %+v, expected max_connections only", report.Changes)
+	}
+	if len(report.Warnings) != 3 {
+		t.Errorf("Test Reconcile failed: actual warnings %v, expected port, no_such_variable and version", report.Warnings)
+	}
+	if report, err = Reconcile(testEndpoint3, desired); err != nil {
+		t.Errorf("Test Reconcile again error: %s", err.Error())
+	} else if len(report.Changes) != 0 {
+		t.Errorf("Test Reconcile again failed: actual changes %+v, expected none", report.Changes)
+	}
+}
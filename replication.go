@@ -0,0 +1,491 @@
+package msops
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ChangeMasterOptions represents the optional clauses of "CHANGE MASTER TO".
+//
+// Zero values are treated as unset and the corresponding clauses are not generated,
+// so the server keeps its current (or default) settings for them. The pointer fields can be
+// set to zero or false explicitly, e.g. Delay: Int(0) generates MASTER_DELAY=0.
+//
+// Clause specification can be found at https://dev.mysql.com/doc/refman/5.6/en/change-master-to.html
+type ChangeMasterOptions struct {
+	// UseGTID uses MASTER_AUTO_POSITION=1 instead of binlog file and position.
+	UseGTID bool
+
+	// MasterLogFile and MasterLogPos specify the binlog coordinates explicitly.
+	// They can't be used together with UseGTID.
+	MasterLogFile string
+	MasterLogPos  int
+
+	// RetryCount=Int(0) retries infinitely, and HeartbeatPeriod=Float(0) disables the heartbeats.
+	ConnectRetry    *int
+	RetryCount      *int
+	HeartbeatPeriod *float64
+	Delay           *int
+	Bind            string
+
+	// SSL generates MASTER_SSL=1 or MASTER_SSL=0.
+	// The other SSL options, except disabling SSLVerifyServerCert, require SSL to be true.
+	SSL                 *bool
+	SSLCA               string
+	SSLCAPath           string
+	SSLCert             string
+	SSLCipher           string
+	SSLKey              string
+	SSLCrl              string
+	SSLCrlPath          string
+	SSLVerifyServerCert *bool
+
+	// GetMasterPublicKey generates GET_MASTER_PUBLIC_KEY, which is only supported since MySQL 8.0.4.
+	GetMasterPublicKey *bool
+
+	// Validate runs ValidateReplicationPair before changing master,
+	// and refuses to change master if any problem is found.
+	Validate bool
+}
+
+// ReplicationCheck represents the result of ValidateReplicationPair.
+//
+// Problems prevent the replication from working correctly, while Warnings may cause
+// inconsistency or failover issues later.
+type ReplicationCheck struct {
+	Problems []string
+	Warnings []string
+}
+
+// maxHeartbeatPeriod is the upper limit of MASTER_HEARTBEAT_PERIOD in seconds.
+const maxHeartbeatPeriod = 4294967
+
+var (
+	errGTIDWithPosition     = errors.New("binlog file and position can't be specified with GTID auto position")
+	errPositionNoFile       = errors.New("binlog position is specified without binlog file")
+	errNegativeOption       = errors.New("numeric option can't be negative")
+	errHeartbeatTooLarge    = errors.New("heartbeat period exceeds " + strconv.Itoa(maxHeartbeatPeriod) + " seconds")
+	errSSLNotEnabled        = errors.New("ssl options are specified without enabling ssl")
+	errPublicKeyUnsupported = errors.New("GET_MASTER_PUBLIC_KEY is supported since MySQL 8.0.4")
+)
+
+// Int returns a pointer to v, which can be used as an explicit option value.
+func Int(v int) *int {
+	return &v
+}
+
+// Float returns a pointer to v, which can be used as an explicit option value.
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Bool returns a pointer to v, which can be used as an explicit option value.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// validate checks whether the options are consistent.
+func (opts ChangeMasterOptions) validate() error {
+	if opts.UseGTID && (opts.MasterLogFile != "" || opts.MasterLogPos != 0) {
+		return errGTIDWithPosition
+	}
+	if opts.MasterLogFile == "" && opts.MasterLogPos != 0 {
+		return errPositionNoFile
+	}
+	for _, value := range []*int{opts.ConnectRetry, opts.RetryCount, opts.Delay} {
+		if value != nil && *value < 0 {
+			return errNegativeOption
+		}
+	}
+	if opts.MasterLogPos < 0 || (opts.HeartbeatPeriod != nil && *opts.HeartbeatPeriod < 0) {
+		return errNegativeOption
+	}
+	if opts.HeartbeatPeriod != nil && *opts.HeartbeatPeriod > maxHeartbeatPeriod {
+		return errHeartbeatTooLarge
+	}
+	sslEnabled := opts.SSL != nil && *opts.SSL
+	verifyEnabled := opts.SSLVerifyServerCert != nil && *opts.SSLVerifyServerCert
+	if !sslEnabled && (opts.SSLCA != "" || opts.SSLCAPath != "" || opts.SSLCert != "" ||
+		opts.SSLCipher != "" || opts.SSLKey != "" || opts.SSLCrl != "" ||
+		opts.SSLCrlPath != "" || verifyEnabled) {
+		return errSSLNotEnabled
+	}
+	return nil
+}
+
+// clauses returns the clauses with placeholders and their args for the options
+// other than the master coordinates.
+func (opts ChangeMasterOptions) clauses() ([]string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	appendInt := func(clause string, value *int) {
+		if value != nil {
+			clauses = append(clauses, clause)
+			args = append(args, *value)
+		}
+	}
+	appendBool := func(clause string, value *bool) {
+		if value != nil {
+			clauses = append(clauses, clause)
+			if *value {
+				args = append(args, 1)
+			} else {
+				args = append(args, 0)
+			}
+		}
+	}
+	appendString := func(clause, value string) {
+		if value != "" {
+			clauses = append(clauses, clause)
+			args = append(args, value)
+		}
+	}
+	appendInt("MASTER_CONNECT_RETRY=?", opts.ConnectRetry)
+	appendInt("MASTER_RETRY_COUNT=?", opts.RetryCount)
+	if opts.HeartbeatPeriod != nil {
+		clauses = append(clauses, "MASTER_HEARTBEAT_PERIOD=?")
+		args = append(args, *opts.HeartbeatPeriod)
+	}
+	appendInt("MASTER_DELAY=?", opts.Delay)
+	appendString("MASTER_BIND=?", opts.Bind)
+	appendBool("MASTER_SSL=?", opts.SSL)
+	appendString("MASTER_SSL_CA=?", opts.SSLCA)
+	appendString("MASTER_SSL_CAPATH=?", opts.SSLCAPath)
+	appendString("MASTER_SSL_CERT=?", opts.SSLCert)
+	appendString("MASTER_SSL_CIPHER=?", opts.SSLCipher)
+	appendString("MASTER_SSL_KEY=?", opts.SSLKey)
+	appendString("MASTER_SSL_CRL=?", opts.SSLCrl)
+	appendString("MASTER_SSL_CRLPATH=?", opts.SSLCrlPath)
+	appendBool("MASTER_SSL_VERIFY_SERVER_CERT=?", opts.SSLVerifyServerCert)
+	appendBool("GET_MASTER_PUBLIC_KEY=?", opts.GetMasterPublicKey)
+	return clauses, args
+}
+
+// OK reports whether no blocking problem is found.
+func (c ReplicationCheck) OK() bool {
+	return len(c.Problems) == 0
+}
+
+func (c *ReplicationCheck) addProblem(format string, args ...interface{}) {
+	c.Problems = append(c.Problems, fmt.Sprintf(format, args...))
+}
+
+func (c *ReplicationCheck) addWarning(format string, args ...interface{}) {
+	c.Warnings = append(c.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ValidateReplicationPair checks whether slaveEndpoint can replicate from masterEndpoint.
+//
+// The following items are checked:
+// both instances are reachable,
+// server_id and server_uuid are unique,
+// log_bin is enabled on the master,
+// binlog_format and binlog_row_image are compatible,
+// gtid_mode and enforce_gtid_consistency are compatible,
+// the slave is not older than the master,
+// and the replUser of the master can connect to it with REPLICATION SLAVE privilege.
+//
+// An error is returned only if the instances are not registered or the variables can't be read.
+func ValidateReplicationPair(slaveEndpoint, masterEndpoint string) (ReplicationCheck, error) {
+	var check ReplicationCheck
+	var masterInst *Instance
+	var exists bool
+	if _, exists = getInstance(slaveEndpoint); !exists {
+		return check, errNotRegistered
+	}
+	if masterInst, exists = getInstance(masterEndpoint); !exists {
+		return check, errNotRegistered
+	}
+	if slaveEndpoint == masterEndpoint {
+		check.addProblem("slave and master are the same endpoint %s", masterEndpoint)
+		return check, nil
+	}
+	if CheckInstance(slaveEndpoint) != InstanceOK {
+		check.addProblem("slave %s is unreachable", slaveEndpoint)
+	}
+	if CheckInstance(masterEndpoint) != InstanceOK {
+		check.addProblem("master %s is unreachable", masterEndpoint)
+	}
+	if !check.OK() {
+		return check, nil
+	}
+
+	var slaveVars, masterVars map[string]string
+	var err error
+	if slaveVars, err = GetGlobalVariables(slaveEndpoint, "%"); err != nil {
+		return check, err
+	}
+	if masterVars, err = GetGlobalVariables(masterEndpoint, "%"); err != nil {
+		return check, err
+	}
+
+	if slaveVars["server_id"] == "0" || masterVars["server_id"] == "0" {
+		check.addProblem("server_id must not be 0")
+	} else if slaveVars["server_id"] == masterVars["server_id"] {
+		check.addProblem("slave and master have the same server_id %s", slaveVars["server_id"])
+	}
+	if uuid := slaveVars["server_uuid"]; uuid != "" && uuid == masterVars["server_uuid"] {
+		check.addProblem("slave and master have the same server_uuid %s", uuid)
+	}
+
+	if !getBool(onOffToBool(masterVars["log_bin"])) {
+		check.addProblem("log_bin is disabled on master")
+	}
+	if !getBool(onOffToBool(slaveVars["log_bin"])) {
+		check.addWarning("log_bin is disabled on slave, it can't be promoted as a master")
+	}
+	if masterVars["binlog_format"] != slaveVars["binlog_format"] {
+		if masterVars["binlog_format"] == "ROW" && slaveVars["binlog_format"] == "STATEMENT" &&
+			getBool(onOffToBool(slaveVars["log_slave_updates"])) {
+			check.addProblem("slave with binlog_format STATEMENT can't log row events from master")
+		} else {
+			check.addWarning("binlog_format differs: master %s, slave %s", masterVars["binlog_format"], slaveVars["binlog_format"])
+		}
+	}
+	if masterVars["binlog_row_image"] != slaveVars["binlog_row_image"] {
+		check.addWarning("binlog_row_image differs: master %s, slave %s", masterVars["binlog_row_image"], slaveVars["binlog_row_image"])
+	}
+
+	masterGTID, slaveGTID := masterVars["gtid_mode"], slaveVars["gtid_mode"]
+	switch {
+	case masterGTID == slaveGTID:
+	case masterGTID == "ON" && slaveGTID == "OFF", masterGTID == "OFF" && slaveGTID == "ON":
+		check.addProblem("gtid_mode is incompatible: master %s, slave %s", masterGTID, slaveGTID)
+	default:
+		check.addWarning("gtid_mode differs: master %s, slave %s", masterGTID, slaveGTID)
+	}
+	if masterVars["enforce_gtid_consistency"] != slaveVars["enforce_gtid_consistency"] {
+		check.addWarning("enforce_gtid_consistency differs: master %s, slave %s",
+			masterVars["enforce_gtid_consistency"], slaveVars["enforce_gtid_consistency"])
+	}
+
+	masterVersion, slaveVersion := parseVersion(masterVars["version"]), parseVersion(slaveVars["version"])
+	if slaveVersion.compare(masterVersion) < 0 {
+		check.addProblem("slave version %s is older than master version %s", slaveVersion, masterVersion)
+	} else if slaveVersion.major != masterVersion.major || slaveVersion.minor != masterVersion.minor {
+		check.addWarning("slave version %s and master version %s are not in the same release series", slaveVersion, masterVersion)
+	}
+
+	masterSemiSync, _ := semiSyncValue(masterVars, "rpl_semi_sync_master_enabled")
+	slaveSemiSync, _ := semiSyncValue(slaveVars, "rpl_semi_sync_slave_enabled")
+	if getBool(onOffToBool(masterSemiSync)) && !getBool(onOffToBool(slaveSemiSync)) {
+		check.addWarning("semi-sync is enabled on master but not on slave, the slave won't acknowledge transactions")
+	}
+
+	if granted, err := hasReplicationGrant(masterEndpoint, masterInst); err != nil {
+		check.addProblem("repl user %s can't connect to master: %s", masterInst.replUser, err.Error())
+	} else if !granted {
+		check.addProblem("repl user %s lacks REPLICATION SLAVE privilege on master", masterInst.replUser)
+	}
+	return check, nil
+}
+
+// hasReplicationGrant connects to the endpoint as the replUser of inst,
+// and checks whether it has REPLICATION SLAVE privilege.
+func hasReplicationGrant(endpoint string, inst *Instance) (bool, error) {
+	grants, err := readReplUserGrants(endpoint, inst)
+	if err != nil {
+		return false, err
+	}
+	return len(missingPrivileges(grants, replPrivileges)) == 0, nil
+}
+
+// onOffToBool converts the "ON"/"OFF" value of variables to "true"/"false".
+func onOffToBool(value string) string {
+	switch strings.ToUpper(value) {
+	case "ON":
+		return "true"
+	case "OFF":
+		return "false"
+	}
+	return value
+}
+
+// SkipOptions describes the replication event to be skipped by SkipReplicationEvent.
+type SkipOptions struct {
+	// ExpectedErrno must be the same as 'Last_SQL_Errno' of the slave, confirming the error to be skipped.
+	ExpectedErrno int
+
+	// Count is the number of event groups skipped by sql_slave_skip_counter in position mode, 1 by default.
+	// It's ignored in GTID mode, where exactly one transaction is skipped.
+	Count int
+}
+
+// SkipReport records what SkipReplicationEvent skipped.
+type SkipReport struct {
+	// GTIDMode implies that an empty transaction of SkippedGTID is injected,
+	// otherwise SkipCounter event groups are skipped from RelayMasterLogFile and ExecMasterLogPos.
+	GTIDMode           bool
+	SkippedGTID        string
+	SkipCounter        int
+	RelayMasterLogFile string
+	ExecMasterLogPos   int
+
+	// SkippedEvents are the relay log events of the skipped event groups in position mode,
+	// read from 'Relay_Log_File' and 'Relay_Log_Pos' of the slave.
+	SkippedEvents []BinlogEvent
+
+	LastSQLErrno int
+	LastSQLError string
+}
+
+var (
+	errNotSlave         = errors.New("the instance is not a slave")
+	errNoSQLError       = errors.New("the SQL thread has no error to skip")
+	errSkipNotConfirmed = errors.New("the expected errno is not specified")
+	errGtidNotFound     = errors.New("the failed GTID can't be determined")
+)
+
+// failedTransactionExp matches the GTID in the error message of the applier,
+// e.g. "Worker 1 failed executing transaction '3E11FA47-71CA-11E1-9E33-C80AA9429562:23' at master log ...".
+var failedTransactionExp = regexp.MustCompile(`failed executing transaction '([^']+)'`)
+
+// skipEventsLimit is the maximum number of the relay log events read to describe the skipped event groups.
+const skipEventsLimit = 1000
+
+// SkipReplicationEvent skips the event failing the SQL thread of the slave, and restarts the SQL thread.
+//
+// In GTID mode, an empty transaction is committed with the failed GTID, which is read from the error message
+// of the SQL thread or performance_schema.replication_applier_status_by_worker. If the failed GTID can't be
+// determined, e.g. on MySQL 5.6, errGtidNotFound is returned without skipping.
+// Otherwise sql_slave_skip_counter is set to opts.Count, and the skipped events are read from the relay log.
+//
+// It refuses to skip unless opts.ExpectedErrno is the same as 'Last_SQL_Errno' of the slave.
+func SkipReplicationEvent(slaveEndpoint string, opts SkipOptions) (SkipReport, error) {
+	var report SkipReport
+	var slaveStatus SlaveStatus
+	var variables map[string]string
+	var err error
+	if opts.ExpectedErrno == 0 {
+		return report, errSkipNotConfirmed
+	}
+	if slaveStatus, err = GetSlaveStatus(slaveEndpoint); err != nil {
+		return report, err
+	}
+	if reflect.DeepEqual(emptySlaveStatus, slaveStatus) {
+		return report, errNotSlave
+	}
+	if slaveStatus.LastSQLErrno == 0 {
+		return report, errNoSQLError
+	}
+	if slaveStatus.LastSQLErrno != opts.ExpectedErrno {
+		return report, fmt.Errorf("the SQL thread fails with errno %d, not the expected %d: %s",
+			slaveStatus.LastSQLErrno, opts.ExpectedErrno, slaveStatus.LastSQLError)
+	}
+	if variables, err = GetGlobalVariables(slaveEndpoint, "gtid_mode"); err != nil {
+		return report, err
+	}
+	report.GTIDMode = variables["gtid_mode"] == "ON"
+	report.RelayMasterLogFile = slaveStatus.RelayMasterLogFile
+	report.ExecMasterLogPos = slaveStatus.ExecMasterLogPos
+	report.LastSQLErrno = slaveStatus.LastSQLErrno
+	report.LastSQLError = slaveStatus.LastSQLError
+
+	if report.GTIDMode {
+		if report.SkippedGTID, err = failedGtid(slaveEndpoint, slaveStatus); err != nil {
+			return report, err
+		}
+	} else {
+		report.SkipCounter = opts.Count
+		if report.SkipCounter <= 0 {
+			report.SkipCounter = 1
+		}
+		events, err := ShowRelayLogEvents(slaveEndpoint, slaveStatus.RelayLogFile, slaveStatus.RelayLogPos, skipEventsLimit)
+		if err != nil {
+			return report, err
+		}
+		report.SkippedEvents = eventGroups(events, report.SkipCounter)
+	}
+
+	if err = execute(slaveEndpoint, "STOP SLAVE SQL_THREAD"); err != nil {
+		return report, err
+	}
+	if report.GTIDMode {
+		err = executeSession(slaveEndpoint,
+			sessionQuery{query: "SET GTID_NEXT=?", args: []interface{}{report.SkippedGTID}},
+			sessionQuery{query: "BEGIN"},
+			sessionQuery{query: "COMMIT"},
+			sessionQuery{query: "SET GTID_NEXT='AUTOMATIC'"},
+		)
+	} else {
+		err = SetGlobalVariable(slaveEndpoint, "sql_slave_skip_counter", report.SkipCounter)
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, execute(slaveEndpoint, "START SLAVE SQL_THREAD")
+}
+
+// failedGtid reads the GTID of the transaction failing the SQL thread of the slave, from the error message
+// of "SHOW SLAVE STATUS", or the failed worker of performance_schema.replication_applier_status_by_worker,
+// which is available since MySQL 5.7.
+func failedGtid(slaveEndpoint string, slaveStatus SlaveStatus) (string, error) {
+	if matches := failedTransactionExp.FindStringSubmatch(slaveStatus.LastSQLError); len(matches) == 2 {
+		return checkGtid(matches[1])
+	}
+	version, err := getVersion(slaveEndpoint)
+	if err != nil {
+		return "", err
+	}
+	if !version.atLeast(5, 7, 0) {
+		return "", errGtidNotFound
+	}
+	status, err := GetApplierStatus(slaveEndpoint)
+	if err != nil {
+		return "", err
+	}
+	for _, worker := range status.FailedWorkers() {
+		if worker.LastErrorNumber != slaveStatus.LastSQLErrno {
+			continue
+		}
+		if matches := failedTransactionExp.FindStringSubmatch(worker.LastErrorMessage); len(matches) == 2 {
+			return checkGtid(matches[1])
+		}
+		if worker.ApplyingTransaction != "" {
+			return checkGtid(worker.ApplyingTransaction)
+		}
+		// LAST_SEEN_TRANSACTION of 5.7 is the transaction failing the worker.
+		if !version.atLeast(8, 0, 0) && worker.LastAppliedTransaction != "" {
+			return checkGtid(worker.LastAppliedTransaction)
+		}
+	}
+	return "", errGtidNotFound
+}
+
+// checkGtid returns gtid if it's a single GTID, e.g. "3E11FA47-71CA-11E1-9E33-C80AA9429562:23".
+func checkGtid(gtid string) (string, error) {
+	set, err := parseGtidSet(gtid)
+	if err != nil || len(set) != 1 {
+		return "", errGtidNotFound
+	}
+	for _, intervals := range set {
+		if len(intervals) != 1 || intervals[0].start <= 0 || intervals[0].start != intervals[0].end {
+			return "", errGtidNotFound
+		}
+	}
+	return gtid, nil
+}
+
+// eventGroups returns the events of the first count event groups. An event group ends with an "Xid" event,
+// a "COMMIT" or "ROLLBACK" query, or a query outside "BEGIN", such as DDL.
+func eventGroups(events []BinlogEvent, count int) []BinlogEvent {
+	var inTransaction bool
+	for i, event := range events {
+		if event.EventType == "Query" && event.Query == "BEGIN" {
+			inTransaction = true
+			continue
+		}
+		if event.EventType == "Xid" || event.EventType == "Query" &&
+			(!inTransaction || event.Query == "COMMIT" || event.Query == "ROLLBACK") {
+			inTransaction = false
+			if count--; count == 0 {
+				return events[:i+1]
+			}
+		}
+	}
+	return events
+}
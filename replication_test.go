@@ -0,0 +1,154 @@
+package msops
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangeMasterOptionsValidate(t *testing.T) {
+	invalidOpts := map[string]ChangeMasterOptions{
+		"gtid with position": {UseGTID: true, MasterLogFile: "binlog.000001", MasterLogPos: 4},
+		"position only":      {MasterLogPos: 4},
+		"negative retry":     {ConnectRetry: Int(-1)},
+		"huge heartbeat":     {HeartbeatPeriod: Float(maxHeartbeatPeriod + 1)},
+		"ssl disabled":       {SSLCA: "/etc/mysql/ca.pem"},
+		"negative delay":     {Delay: Int(-1)},
+		"verify without ssl": {SSL: Bool(false), SSLVerifyServerCert: Bool(true)},
+	}
+	for name, opts := range invalidOpts {
+		if opts.validate() == nil {
+			t.Errorf("Test ChangeMasterOptions validate %s: should return error", name)
+		}
+	}
+	validOpts := ChangeMasterOptions{
+		MasterLogFile:       "binlog.000001",
+		MasterLogPos:        4,
+		ConnectRetry:        Int(10),
+		HeartbeatPeriod:     Float(0.5),
+		SSL:                 Bool(true),
+		SSLCA:               "/etc/mysql/ca.pem",
+		SSLVerifyServerCert: Bool(true),
+	}
+	if err := validOpts.validate(); err != nil {
+		t.Errorf("Test ChangeMasterOptions validate error: %s", err.Error())
+	}
+	if clauses, args := validOpts.clauses(); len(clauses) != 5 || len(args) != 5 {
+		t.Errorf("Test ChangeMasterOptions clauses failed: actual %d clauses and %d args, expected 5 and 5", len(clauses), len(args))
+	}
+
+	disableOpts := ChangeMasterOptions{RetryCount: Int(0), HeartbeatPeriod: Float(0), Delay: Int(0),
+		SSL: Bool(false), SSLVerifyServerCert: Bool(false)}
+	if err := disableOpts.validate(); err != nil {
+		t.Errorf("Test ChangeMasterOptions validate disabling error: %s", err.Error())
+	}
+	clauses, args := disableOpts.clauses()
+	expectedClauses := []string{"MASTER_RETRY_COUNT=?", "MASTER_HEARTBEAT_PERIOD=?", "MASTER_DELAY=?",
+		"MASTER_SSL=?", "MASTER_SSL_VERIFY_SERVER_CERT=?"}
+	expectedArgs := []interface{}{0, float64(0), 0, 0, 0}
+	if !reflect.DeepEqual(clauses, expectedClauses) || !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Test ChangeMasterOptions clauses disabling failed: actual %v %v, expected %v %v", clauses, args, expectedClauses, expectedArgs)
+	}
+}
+
+func TestChangeMasterToWithOptions(t *testing.T) {
+	if ChangeMasterToWithOptions(unregisteredEndpoint, testEndpoint1, ChangeMasterOptions{}) != errNotRegistered {
+		t.Error("Test ChangeMasterToWithOptions unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if ChangeMasterToWithOptions(testEndpoint3, testEndpoint1, ChangeMasterOptions{MasterLogPos: 4}) != errPositionNoFile {
+		t.Error("Test ChangeMasterToWithOptions invalid options error: should return errPositionNoFile")
+	}
+
+	if version, err := getVersion(testEndpoint3); err != nil {
+		t.Errorf("Test getVersion error: %s", err.Error())
+	} else if !version.atLeast(8, 0, 4) {
+		if ChangeMasterToWithOptions(testEndpoint3, testEndpoint1, ChangeMasterOptions{GetMasterPublicKey: Bool(true)}) != errPublicKeyUnsupported {
+			t.Error("Test ChangeMasterToWithOptions GetMasterPublicKey error: should return errPublicKeyUnsupported")
+		}
+	}
+
+	opts := ChangeMasterOptions{ConnectRetry: Int(10), RetryCount: Int(5)}
+	if err := ChangeMasterToWithOptions(testEndpoint3, testEndpoint1, opts); err != nil {
+		t.Errorf("Test ChangeMasterToWithOptions testEndpoint3->testEndpoint1 error: %s", err.Error())
+	} else if slaveSt, err := GetSlaveStatus(testEndpoint3); err != nil {
+		t.Errorf("Test ChangeMasterToWithOptions testEndpoint3 GetSlaveStatus error: %s", err.Error())
+	} else if slaveSt.ConnectRetry != "10" || slaveSt.MasterRetryCount != 5 {
+		t.Errorf("Test ChangeMasterToWithOptions failed: actual connect retry %s and retry count %d, expected 10 and 5",
+			slaveSt.ConnectRetry, slaveSt.MasterRetryCount)
+	}
+	ResetSlave(testEndpoint3, true)
+}
+
+func TestValidateReplicationPair(t *testing.T) {
+	if _, err := ValidateReplicationPair(unregisteredEndpoint, testEndpoint1); err != errNotRegistered {
+		t.Error("Test ValidateReplicationPair unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if check, err := ValidateReplicationPair(badEndpoint, testEndpoint1); err != nil {
+		t.Errorf("Test ValidateReplicationPair badEndpoint error: %s", err.Error())
+	} else if check.OK() {
+		t.Error("Test ValidateReplicationPair badEndpoint failed: should report unreachable problem")
+	}
+	if check, err := ValidateReplicationPair(testEndpoint1, testEndpoint1); err != nil {
+		t.Errorf("Test ValidateReplicationPair same endpoint error: %s", err.Error())
+	} else if check.OK() {
+		t.Error("Test ValidateReplicationPair same endpoint failed: should report problem")
+	}
+	if _, err := ValidateReplicationPair(testEndpoint2, testEndpoint1); err != nil {
+		t.Errorf("Test ValidateReplicationPair testEndpoint2->testEndpoint1 error: %s", err.Error())
+	}
+}
+
+func TestSkipReplicationEvent(t *testing.T) {
+	if _, err := SkipReplicationEvent(testEndpoint2, SkipOptions{}); err != errSkipNotConfirmed {
+		t.Error("Test SkipReplicationEvent without expected errno error: should return errSkipNotConfirmed")
+	}
+	if _, err := SkipReplicationEvent(unregisteredEndpoint, SkipOptions{ExpectedErrno: 1062}); err != errNotRegistered {
+		t.Error("Test SkipReplicationEvent unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := SkipReplicationEvent(badEndpoint, SkipOptions{ExpectedErrno: 1062}); err == nil {
+		t.Error("Test SkipReplicationEvent badEndpoint error: should cause error")
+	}
+	if _, err := SkipReplicationEvent(testEndpoint1, SkipOptions{ExpectedErrno: 1062}); err != errNotSlave {
+		t.Error("Test SkipReplicationEvent testEndpoint1 error: should return errNotSlave")
+	}
+
+	if err := ChangeMasterTo(testEndpoint3, testEndpoint1, false); err != nil {
+		t.Fatalf("Test SkipReplicationEvent ChangeMasterTo error: %s", err.Error())
+	}
+	defer ResetSlave(testEndpoint3, true)
+	if _, err := SkipReplicationEvent(testEndpoint3, SkipOptions{ExpectedErrno: 1062}); err != errNoSQLError {
+		t.Error("Test SkipReplicationEvent healthy slave error: should return errNoSQLError")
+	}
+}
+
+func TestFailedGtidParsing(t *testing.T) {
+	message := "Worker 1 failed executing transaction '3E11FA47-71CA-11E1-9E33-C80AA9429562:23' at master log binlog.000002, end_log_pos 1024"
+	if matches := failedTransactionExp.FindStringSubmatch(message); len(matches) != 2 {
+		t.Fatal("Test failedTransactionExp failed: GTID not matched")
+	} else if gtid, err := checkGtid(matches[1]); err != nil || gtid != "3E11FA47-71CA-11E1-9E33-C80AA9429562:23" {
+		t.Errorf("Test checkGtid failed: actual %s", gtid)
+	}
+	for _, gtid := range []string{"", "ANONYMOUS", "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-3",
+		"3E11FA47-71CA-11E1-9E33-C80AA9429562:1,8a94f357-aab4-11df-86ab-c80aa9429562:1"} {
+		if _, err := checkGtid(gtid); err != errGtidNotFound {
+			t.Errorf("Test checkGtid %q error: should return errGtidNotFound", gtid)
+		}
+	}
+}
+
+func TestEventGroups(t *testing.T) {
+	events := []BinlogEvent{
+		{EventType: "Query", Query: "BEGIN"},
+		{EventType: "Table_map"},
+		{EventType: "Write_rows"},
+		{EventType: "Xid"},
+		{EventType: "Query", Query: "CREATE TABLE t (id int)"},
+		{EventType: "Query", Query: "BEGIN"},
+		{EventType: "Query", Query: "INSERT INTO t VALUES (1)"},
+		{EventType: "Query", Query: "COMMIT"},
+	}
+	for count, expected := range map[int]int{1: 4, 2: 5, 3: 8, 4: 8} {
+		if groups := eventGroups(events, count); len(groups) != expected {
+			t.Errorf("Test eventGroups failed: actual %d events of %d groups, expected %d", len(groups), count, expected)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package msops
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SemiSyncMasterStatus represents the status of the semi-synchronous replication master plugin.
+//
+// Since MySQL 8.0.26 the plugin is named rpl_semi_sync_source, whose variables are read
+// into the same fields.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.7/en/replication-semisync-monitoring.html
+type SemiSyncMasterStatus struct {
+	Installed bool
+
+	// Enabled is the value of rpl_semi_sync_master_enabled,
+	// while Active is 'Rpl_semi_sync_master_status', which turns off when the master falls back to asynchronous replication.
+	Enabled bool
+	Active  bool
+
+	// Timeout is in milliseconds. WaitPoint is empty before MySQL 5.7.2.
+	Timeout           int
+	WaitPoint         string
+	WaitForSlaveCount int
+	WaitNoSlave       bool
+
+	// Clients is the number of semi-sync slaves connected, and WaitSessions is the number of sessions
+	// waiting for the acknowledgement of slaves. NoTimes is the times the master falls back to asynchronous replication.
+	Clients       int
+	WaitSessions  int
+	YesTx         int
+	NoTx          int
+	TxWaits       int
+	NetWaits      int
+	TxAvgWaitTime int
+	NoTimes       int
+}
+
+// SemiSyncSlaveStatus represents the status of the semi-synchronous replication slave plugin.
+type SemiSyncSlaveStatus struct {
+	Installed bool
+	Enabled   bool
+	Active    bool
+}
+
+// SemiSyncStatus represents the semi-synchronous replication status of both roles of one endpoint.
+type SemiSyncStatus struct {
+	Master SemiSyncMasterStatus
+	Slave  SemiSyncSlaveStatus
+}
+
+// SemiSyncHealth represents the health of the semi-synchronous replication of a master.
+type SemiSyncHealth int
+
+const (
+	// SemiSyncOK implies that the semi-sync master is enabled and active, with no sessions waiting for acknowledgement.
+	SemiSyncOK SemiSyncHealth = iota
+
+	// SemiSyncWaiting implies that the semi-sync master is active,
+	// but some sessions are waiting for the acknowledgement of slaves.
+	SemiSyncWaiting
+
+	// SemiSyncFallback implies that the semi-sync master is enabled but not active,
+	// i.e. it has fallen back to asynchronous replication after rpl_semi_sync_master_timeout.
+	SemiSyncFallback
+
+	// SemiSyncDisabled implies that the semi-sync master plugin is not installed or not enabled.
+	SemiSyncDisabled
+
+	// SemiSyncUnknown implies that the semi-sync status can't be read.
+	SemiSyncUnknown
+)
+
+// Semi-synchronous replication wait points of rpl_semi_sync_master_wait_point.
+const (
+	SemiSyncAfterSync   = "AFTER_SYNC"
+	SemiSyncAfterCommit = "AFTER_COMMIT"
+)
+
+var (
+	errSemiSyncNotInstalled = errors.New("the semi-sync plugin is not installed")
+	errWaitPointInvalid     = errors.New("the semi-sync wait point is not valid")
+	errWaitPointUnsupported = errors.New("the semi-sync wait point is supported since MySQL 5.7.2")
+
+	// semiSyncRenamer renames the variables of the legacy plugins to the ones since MySQL 8.0.26.
+	semiSyncRenamer = strings.NewReplacer("master", "source", "slave", "replica")
+)
+
+// GetSemiSyncStatus reads the semi-synchronous replication variables and status of the endpoint.
+// The fields of a role are left empty if its plugin is not installed.
+func GetSemiSyncStatus(endpoint string) (SemiSyncStatus, error) {
+	var status SemiSyncStatus
+	var variables, globalStatus map[string]string
+	var err error
+	if variables, err = GetGlobalVariables(endpoint, "rpl_semi_sync_%"); err != nil {
+		return status, err
+	}
+	if globalStatus, err = GetGlobalStatus(endpoint, "Rpl_semi_sync_%"); err != nil {
+		return status, err
+	}
+
+	if enabled, exists := semiSyncValue(variables, "rpl_semi_sync_master_enabled"); exists {
+		status.Master.Installed = true
+		status.Master.Enabled = getBool(onOffToBool(enabled))
+		status.Master.Active = getBool(onOffToBool(semiSyncGet(globalStatus, "Rpl_semi_sync_master_status")))
+		status.Master.Timeout = getInt(semiSyncGet(variables, "rpl_semi_sync_master_timeout"))
+		status.Master.WaitPoint = semiSyncGet(variables, "rpl_semi_sync_master_wait_point")
+		status.Master.WaitForSlaveCount = getInt(semiSyncGet(variables, "rpl_semi_sync_master_wait_for_slave_count"))
+		status.Master.WaitNoSlave = getBool(onOffToBool(semiSyncGet(variables, "rpl_semi_sync_master_wait_no_slave")))
+		status.Master.Clients = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_clients"))
+		status.Master.WaitSessions = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_wait_sessions"))
+		status.Master.YesTx = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_yes_tx"))
+		status.Master.NoTx = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_no_tx"))
+		status.Master.TxWaits = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_tx_waits"))
+		status.Master.NetWaits = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_net_waits"))
+		status.Master.TxAvgWaitTime = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_tx_avg_wait_time"))
+		status.Master.NoTimes = getInt(semiSyncGet(globalStatus, "Rpl_semi_sync_master_no_times"))
+	}
+	if enabled, exists := semiSyncValue(variables, "rpl_semi_sync_slave_enabled"); exists {
+		status.Slave.Installed = true
+		status.Slave.Enabled = getBool(onOffToBool(enabled))
+		status.Slave.Active = getBool(onOffToBool(semiSyncGet(globalStatus, "Rpl_semi_sync_slave_status")))
+	}
+	return status, nil
+}
+
+// CheckSemiSync checks the health of the semi-synchronous replication of masterEndpoint.
+func CheckSemiSync(masterEndpoint string) SemiSyncHealth {
+	status, err := GetSemiSyncStatus(masterEndpoint)
+	if err != nil {
+		return SemiSyncUnknown
+	}
+	return status.Master.health()
+}
+
+func (status SemiSyncMasterStatus) health() SemiSyncHealth {
+	switch {
+	case !status.Installed || !status.Enabled:
+		return SemiSyncDisabled
+	case !status.Active:
+		return SemiSyncFallback
+	case status.WaitSessions > 0:
+		return SemiSyncWaiting
+	}
+	return SemiSyncOK
+}
+
+// InstallSemiSyncPlugins installs the semi-synchronous replication master plugin if master is true,
+// and the slave plugin if slave is true. The plugins already installed are skipped.
+//
+// Since MySQL 8.0.26, rpl_semi_sync_source and rpl_semi_sync_replica are installed instead.
+func InstallSemiSyncPlugins(endpoint string, master, slave bool) error {
+	var version serverVersion
+	var status SemiSyncStatus
+	var err error
+	if version, err = getVersion(endpoint); err != nil {
+		return err
+	}
+	if status, err = GetSemiSyncStatus(endpoint); err != nil {
+		return err
+	}
+	masterPlugin, masterLib := "rpl_semi_sync_master", "semisync_master"
+	slavePlugin, slaveLib := "rpl_semi_sync_slave", "semisync_slave"
+	if version.atLeast(8, 0, 26) {
+		masterPlugin, masterLib = "rpl_semi_sync_source", "semisync_source"
+		slavePlugin, slaveLib = "rpl_semi_sync_replica", "semisync_replica"
+	}
+	if master && !status.Master.Installed {
+		if err = execute(endpoint, "INSTALL PLUGIN "+masterPlugin+" SONAME '"+masterLib+".so'"); err != nil {
+			return err
+		}
+	}
+	if slave && !status.Slave.Installed {
+		if err = execute(endpoint, "INSTALL PLUGIN "+slavePlugin+" SONAME '"+slaveLib+".so'"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnableSemiSyncMaster sets rpl_semi_sync_master_enabled of the endpoint.
+func EnableSemiSyncMaster(endpoint string, enabled bool) error {
+	return setSemiSyncVariable(endpoint, "rpl_semi_sync_master_enabled", enabled)
+}
+
+// EnableSemiSyncSlave sets rpl_semi_sync_slave_enabled of the endpoint.
+// The slave I/O thread is restarted if it's running, so that the setting takes effect immediately.
+func EnableSemiSyncSlave(endpoint string, enabled bool) error {
+	var slaveStatus SlaveStatus
+	var err error
+	if err = setSemiSyncVariable(endpoint, "rpl_semi_sync_slave_enabled", enabled); err != nil {
+		return err
+	}
+	if slaveStatus, err = GetSlaveStatus(endpoint); err != nil {
+		return err
+	}
+	if slaveStatus.SlaveIORunning != "Yes" {
+		return nil
+	}
+	if err = execute(endpoint, "STOP SLAVE IO_THREAD"); err != nil {
+		return err
+	}
+	return execute(endpoint, "START SLAVE IO_THREAD")
+}
+
+// SetSemiSyncTimeout sets rpl_semi_sync_master_timeout of the endpoint,
+// after which the master falls back to asynchronous replication.
+func SetSemiSyncTimeout(endpoint string, timeout time.Duration) error {
+	if timeout < 0 {
+		return errNegativeOption
+	}
+	return setSemiSyncVariable(endpoint, "rpl_semi_sync_master_timeout", int64(timeout/time.Millisecond))
+}
+
+// SetSemiSyncWaitPoint sets rpl_semi_sync_master_wait_point of the endpoint,
+// which is SemiSyncAfterSync or SemiSyncAfterCommit.
+func SetSemiSyncWaitPoint(endpoint, waitPoint string) error {
+	var version serverVersion
+	var err error
+	waitPoint = strings.ToUpper(waitPoint)
+	if waitPoint != SemiSyncAfterSync && waitPoint != SemiSyncAfterCommit {
+		return errWaitPointInvalid
+	}
+	if version, err = getVersion(endpoint); err != nil {
+		return err
+	}
+	if !version.atLeast(5, 7, 2) {
+		return errWaitPointUnsupported
+	}
+	return setSemiSyncVariable(endpoint, "rpl_semi_sync_master_wait_point", waitPoint)
+}
+
+// setSemiSyncVariable sets the variable of the legacy plugin name, or the renamed one if the new plugin is installed.
+func setSemiSyncVariable(endpoint, key string, value interface{}) error {
+	var variables map[string]string
+	var err error
+	if variables, err = GetGlobalVariables(endpoint, "rpl_semi_sync_%"); err != nil {
+		return err
+	}
+	if _, exists := variables[key]; !exists {
+		if _, exists = variables[semiSyncRenamer.Replace(key)]; !exists {
+			return errSemiSyncNotInstalled
+		}
+		key = semiSyncRenamer.Replace(key)
+	}
+	return SetGlobalVariable(endpoint, key, value)
+}
+
+// semiSyncValue looks up the variable or status of the legacy plugin name, and then the renamed one.
+func semiSyncValue(values map[string]string, key string) (string, bool) {
+	if value, exists := values[key]; exists {
+		return value, true
+	}
+	value, exists := values[semiSyncRenamer.Replace(key)]
+	return value, exists
+}
+
+func semiSyncGet(values map[string]string, key string) string {
+	value, _ := semiSyncValue(values, key)
+	return value
+}
@@ -0,0 +1,99 @@
+package msops
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSemiSyncStatus(t *testing.T) {
+	if _, err := GetSemiSyncStatus(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test GetSemiSyncStatus unregisteredEndpoint error: should return errNotRegistered")
+	}
+	if _, err := GetSemiSyncStatus(badEndpoint); err == nil {
+		t.Error("Get badEndpoint semi-sync status should cause error")
+	}
+	if status, err := GetSemiSyncStatus(testEndpoint2); err != nil {
+		t.Errorf("Test GetSemiSyncStatus error: %s", err.Error())
+	} else if status.Master.Installed && status.Master.Active && !status.Master.Enabled {
+		t.Error("Test GetSemiSyncStatus failed: master is active but not enabled")
+	}
+}
+
+func TestSemiSyncManagement(t *testing.T) {
+	if SetSemiSyncWaitPoint(testEndpoint3, "BEFORE_COMMIT") != errWaitPointInvalid {
+		t.Error("Test SetSemiSyncWaitPoint invalid wait point error: should return errWaitPointInvalid")
+	}
+	if SetSemiSyncTimeout(testEndpoint3, -time.Second) != errNegativeOption {
+		t.Error("Test SetSemiSyncTimeout negative timeout error: should return errNegativeOption")
+	}
+	if err := InstallSemiSyncPlugins(testEndpoint3, true, true); err != nil {
+		t.Fatalf("Test InstallSemiSyncPlugins error: %s", err.Error())
+	}
+	// Installing again should skip the installed plugins.
+	if err := InstallSemiSyncPlugins(testEndpoint3, true, true); err != nil {
+		t.Fatalf("Test InstallSemiSyncPlugins again error: %s", err.Error())
+	}
+	if err := EnableSemiSyncMaster(testEndpoint3, true); err != nil {
+		t.Errorf("Test EnableSemiSyncMaster error: %s", err.Error())
+	}
+	defer EnableSemiSyncMaster(testEndpoint3, false)
+	if err := EnableSemiSyncSlave(testEndpoint3, true); err != nil {
+		t.Errorf("Test EnableSemiSyncSlave error: %s", err.Error())
+	}
+	defer EnableSemiSyncSlave(testEndpoint3, false)
+	if err := SetSemiSyncTimeout(testEndpoint3, 3*time.Second); err != nil {
+		t.Errorf("Test SetSemiSyncTimeout error: %s", err.Error())
+	}
+
+	status, err := GetSemiSyncStatus(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test GetSemiSyncStatus error: %s", err.Error())
+	}
+	if !status.Master.Installed || !status.Master.Enabled || status.Master.Timeout != 3000 {
+		t.Errorf("Test semi-sync master failed: unexpected status %+v", status.Master)
+	}
+	if !status.Slave.Installed || !status.Slave.Enabled {
+		t.Errorf("Test semi-sync slave failed: unexpected status %+v", status.Slave)
+	}
+
+	version, err := getVersion(testEndpoint3)
+	if err != nil {
+		t.Fatalf("Test getVersion error: %s", err.Error())
+	}
+	if err = SetSemiSyncWaitPoint(testEndpoint3, SemiSyncAfterSync); version.atLeast(5, 7, 2) && err != nil {
+		t.Errorf("Test SetSemiSyncWaitPoint error: %s", err.Error())
+	} else if !version.atLeast(5, 7, 2) && err != errWaitPointUnsupported {
+		t.Error("Test SetSemiSyncWaitPoint before 5.7.2 error: should return errWaitPointUnsupported")
+	}
+}
+
+func TestSemiSyncValue(t *testing.T) {
+	values := map[string]string{"rpl_semi_sync_source_wait_for_replica_count": "2"}
+	if value, exists := semiSyncValue(values, "rpl_semi_sync_master_wait_for_slave_count"); !exists || value != "2" {
+		t.Errorf("Test semiSyncValue failed: actual %s, expected 2", value)
+	}
+	if _, exists := semiSyncValue(values, "rpl_semi_sync_master_enabled"); exists {
+		t.Error("Test semiSyncValue failed: rpl_semi_sync_master_enabled should not exist")
+	}
+}
+
+func TestSemiSyncHealth(t *testing.T) {
+	if CheckSemiSync(unregisteredEndpoint) != SemiSyncUnknown {
+		t.Error("Test CheckSemiSync unregisteredEndpoint failed: should return SemiSyncUnknown")
+	}
+	cases := []struct {
+		status   SemiSyncMasterStatus
+		expected SemiSyncHealth
+	}{
+		{SemiSyncMasterStatus{}, SemiSyncDisabled},
+		{SemiSyncMasterStatus{Installed: true}, SemiSyncDisabled},
+		{SemiSyncMasterStatus{Installed: true, Enabled: true, NoTimes: 1}, SemiSyncFallback},
+		{SemiSyncMasterStatus{Installed: true, Enabled: true, Active: true, WaitSessions: 2}, SemiSyncWaiting},
+		{SemiSyncMasterStatus{Installed: true, Enabled: true, Active: true, Clients: 1}, SemiSyncOK},
+	}
+	for _, c := range cases {
+		if health := c.status.health(); health != c.expected {
+			t.Errorf("Test SemiSyncMasterStatus health failed: actual %d, expected %d of %+v", health, c.expected, c.status)
+		}
+	}
+}
@@ -4,6 +4,9 @@ package msops
 // Based on 5.6.30-log MySQL Community Server.
 //
 // Field specification can be found at https://dev.mysql.com/doc/refman/5.6/en/show-processlist.html
+//
+// The fields after Info are only filled by GetFullProcessList, and are left empty
+// if the server doesn't provide them.
 type Process struct {
 	ID      int
 	User    string
@@ -13,6 +16,13 @@ type Process struct {
 	Time    int
 	State   string
 	Info    string
+
+	TimeMs         int
+	ThreadID       int
+	ThreadOSID     int
+	ConnectionType string
+	TrxState       string
+	TrxStarted     string
 }
 
 // MasterStatus represents the master status of one endpoint.
@@ -93,7 +103,8 @@ type SlaveStatus struct {
 //
 // Field specification can be found at https://dev.mysql.com/doc/refman/5.6/en/innodb-standard-monitor.html
 type InnoDBStatus struct {
-	InnodbMutexSpinWaits  int
-	InnodbMutexSpinRounds int
-	InnodbMutexOSWaits    int
+	InnodbMutexSpinWaits    int
+	InnodbMutexSpinRounds   int
+	InnodbMutexOSWaits      int
+	InnodbHistoryListLength int
 }
@@ -0,0 +1,18 @@
+#!/usr/bin/env bash
+# Captures the binary logs of capture.sql from real MySQL servers as the fixtures of TestBinlogFixtures.
+set -e
+
+cd "$(dirname "$0")"
+
+for version in 5.6.30 5.7.40 8.0.32
+do
+    name=msops_binlog_$version
+    docker run --name $name -e MYSQL_ALLOW_EMPTY_PASSWORD=yes -e MYSQL_ROOT_HOST=% -p 3399:3306 -d mysql/mysql-server:$version \
+        --log-bin=binlog --server-id=1 --binlog-checksum=CRC32 --binlog-format=ROW
+    sleep 30
+    mysql -uroot -h127.0.0.1 -P3399 < capture.sql
+    # The binary logs are flushed around the captured statements, so they're in the second to last one.
+    file=$(mysql -uroot -h127.0.0.1 -P3399 -N -e "SHOW BINARY LOGS" | tail -2 | head -1 | cut -f1)
+    docker cp $name:/var/lib/mysql/$file mysql-$version.bin
+    docker rm -f $name
+done
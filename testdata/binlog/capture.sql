@@ -0,0 +1,37 @@
+-- The statements whose binary logs are captured as the fixtures of TestBinlogFixtures.
+SET SESSION binlog_format = 'ROW';
+SET SESSION time_zone = '+00:00';
+CREATE DATABASE IF NOT EXISTS data_test;
+CREATE TABLE data_test.tbl_types (
+    c_tiny TINYINT,
+    c_short SMALLINT,
+    c_int24 MEDIUMINT,
+    c_long INT,
+    c_longlong BIGINT,
+    c_float FLOAT,
+    c_double DOUBLE,
+    c_decimal DECIMAL(20, 6),
+    c_year YEAR,
+    c_date DATE,
+    c_time TIME(3),
+    c_datetime DATETIME(6),
+    c_timestamp TIMESTAMP NULL,
+    c_char CHAR(10),
+    c_varchar VARCHAR(300),
+    c_blob BLOB,
+    c_text TEXT,
+    c_bit BIT(10),
+    c_enum ENUM('a', 'b', 'c'),
+    c_set SET('x', 'y', 'z'),
+    c_null INT
+) DEFAULT CHARSET = utf8;
+FLUSH BINARY LOGS;
+INSERT INTO data_test.tbl_types VALUES (
+    -128, -32768, -8388608, -2147483648, -9223372036854775808,
+    1.5, -2.25, -12345678901234.567890, 2026, '2026-10-18',
+    '-12:34:56.789', '2026-10-18 12:34:56.123456', '2026-10-18 12:34:56',
+    'char', REPEAT('v', 300), 'blob', 'text', b'1010101010', 'b', 'x,z', NULL
+);
+UPDATE data_test.tbl_types SET c_long = 42, c_decimal = 0.5;
+DELETE FROM data_test.tbl_types;
+FLUSH BINARY LOGS;
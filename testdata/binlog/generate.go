@@ -0,0 +1,434 @@
+//go:build ignore
+// +build ignore
+
+// generate writes the binary logs of capture.sql in the on-disk formats of MySQL 5.6.30, 5.7.40 and 8.0.32
+// (row format, CRC32 checksum), as generated-<version>.bin. It's independent of the msops decoder, following
+// the encoding of sql/log_event.cc, sql/rpl_utility.cc and sql-common/my_time.c, so that the decoder is tested
+// against the layouts of each version rather than its own assumptions.
+//
+// The files captured from the real servers by capture.sh (mysql-<version>.bin) are decoded by the same test.
+//
+// Usage: go run testdata/binlog/generate.go
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	queryEvent         = 2
+	rotateEvent        = 4
+	formatDescription  = 15
+	xidEvent           = 16
+	tableMapEvent      = 19
+	writeRowsEventV2   = 30
+	updateRowsEventV2  = 31
+	deleteRowsEventV2  = 32
+	anonymousGtidEvent = 34
+	previousGtidsEvent = 35
+
+	serverID  = 1
+	timestamp = 1792326896 // 2026-10-18 12:34:56 UTC
+	tableID   = 108
+	threadID  = 2
+)
+
+// server describes the differences of the binlog layouts between versions.
+type server struct {
+	version string
+	// eventTypes is the number of event types known by the version, i.e. the length of the post header lengths.
+	eventTypes int
+	// gtidPostHeader is the post header length of GTID events, 25 in 5.6 and 42 with the logical clock since 5.7.
+	gtidPostHeader int
+	// anonymousGtids are written before each transaction with gtid_mode=OFF since 5.7.6.
+	anonymousGtids bool
+	// previousGtids is written after the format description since 5.7, and 5.6 with gtid_mode=ON only.
+	previousGtids bool
+	// optionalMetadata is appended to table map events since 8.0.1.
+	optionalMetadata bool
+}
+
+var servers = []server{
+	{version: "5.6.30-log", eventTypes: 35, gtidPostHeader: 25},
+	{version: "5.7.40-log", eventTypes: 38, gtidPostHeader: 42, anonymousGtids: true, previousGtids: true},
+	{version: "8.0.32", eventTypes: 41, gtidPostHeader: 42, anonymousGtids: true, previousGtids: true, optionalMetadata: true},
+}
+
+// writer appends the events with the header, the log position and the CRC32 checksum.
+type writer struct {
+	bytes.Buffer
+}
+
+func (w *writer) event(eventType byte, flags uint16, body []byte) {
+	size := 19 + len(body) + 4
+	var event bytes.Buffer
+	binary.Write(&event, binary.LittleEndian, uint32(timestamp))
+	event.WriteByte(eventType)
+	binary.Write(&event, binary.LittleEndian, uint32(serverID))
+	binary.Write(&event, binary.LittleEndian, uint32(size))
+	binary.Write(&event, binary.LittleEndian, uint32(w.Len()+size))
+	binary.Write(&event, binary.LittleEndian, flags)
+	event.Write(body)
+	binary.Write(&event, binary.LittleEndian, crc32.ChecksumIEEE(event.Bytes()))
+	w.Write(event.Bytes())
+}
+
+func main() {
+	for _, s := range servers {
+		name := "generated-" + strings.TrimSuffix(s.version, "-log") + ".bin"
+		if err := ioutil.WriteFile(filepath.Join("testdata", "binlog", name), s.binlog(), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (s server) binlog() []byte {
+	w := &writer{}
+	w.WriteString("\xfebin")
+	w.event(formatDescription, 0, s.formatDescription())
+	if s.previousGtids {
+		w.event(previousGtidsEvent, 0, make([]byte, 8))
+	}
+
+	inserted := insertedRow()
+	updated := insertedRow()
+	updated[3] = int32(42)
+	updated[7] = decimalValue{"0", "500000", false}
+	transactions := []struct {
+		eventType byte
+		rows      [][]interface{}
+	}{
+		{writeRowsEventV2, [][]interface{}{inserted}},
+		{updateRowsEventV2, [][]interface{}{inserted, updated}},
+		{deleteRowsEventV2, [][]interface{}{updated}},
+	}
+	for i, transaction := range transactions {
+		if s.anonymousGtids {
+			w.event(anonymousGtidEvent, 0, s.anonymousGtid(int64(i)))
+		}
+		w.event(queryEvent, 8, s.query("BEGIN"))
+		w.event(tableMapEvent, 0, s.tableMap())
+		w.event(transaction.eventType, 0, rowsEvent(transaction.eventType, transaction.rows))
+		xid := make([]byte, 8)
+		binary.LittleEndian.PutUint64(xid, uint64(20+i))
+		w.event(xidEvent, 0, xid)
+	}
+
+	rotate := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rotate, 4)
+	w.event(rotateEvent, 0, append(rotate, "binlog.000003"...))
+	return w.Bytes()
+}
+
+func (s server) formatDescription() []byte {
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint16(4))
+	version := make([]byte, 50)
+	copy(version, s.version)
+	body.Write(version)
+	binary.Write(&body, binary.LittleEndian, uint32(0))
+	body.WriteByte(19)
+
+	lengths := make([]byte, s.eventTypes)
+	set := func(eventType, length int) {
+		if eventType <= s.eventTypes {
+			lengths[eventType-1] = byte(length)
+		}
+	}
+	set(1, 56)          // START_EVENT_V3
+	set(queryEvent, 13) // QUERY_EVENT
+	set(rotateEvent, 8) // ROTATE_EVENT
+	set(6, 18)          // LOAD_EVENT
+	set(8, 4)           // CREATE_FILE_EVENT
+	set(9, 4)           // APPEND_BLOCK_EVENT
+	set(10, 4)          // EXEC_LOAD_EVENT
+	set(11, 4)          // DELETE_FILE_EVENT
+	set(12, 18)         // NEW_LOAD_EVENT
+	set(formatDescription, 56+1+s.eventTypes)
+	set(17, 4)            // BEGIN_LOAD_QUERY_EVENT
+	set(18, 26)           // EXECUTE_LOAD_QUERY_EVENT
+	set(tableMapEvent, 8) // TABLE_MAP_EVENT
+	for eventType := 23; eventType <= 25; eventType++ {
+		set(eventType, 8) // ROWS_EVENT_V1
+	}
+	set(26, 2) // INCIDENT_EVENT
+	for eventType := writeRowsEventV2; eventType <= deleteRowsEventV2; eventType++ {
+		set(eventType, 10) // ROWS_EVENT_V2
+	}
+	set(33, s.gtidPostHeader) // GTID_LOG_EVENT
+	set(anonymousGtidEvent, s.gtidPostHeader)
+	set(36, 18) // TRANSACTION_CONTEXT_EVENT
+	set(37, 52) // VIEW_CHANGE_EVENT
+	set(39, 10) // PARTIAL_UPDATE_ROWS_EVENT
+	set(40, 40) // TRANSACTION_PAYLOAD_EVENT
+	body.Write(lengths)
+	body.WriteByte(1) // BINLOG_CHECKSUM_ALG_CRC32
+	return body.Bytes()
+}
+
+func (s server) anonymousGtid(seq int64) []byte {
+	var body bytes.Buffer
+	body.WriteByte(1)            // commit flag
+	body.Write(make([]byte, 16)) // empty SID
+	body.Write(make([]byte, 8))  // GNO 0
+	body.WriteByte(2)            // LOGICAL_TIMESTAMP_TYPECODE
+	binary.Write(&body, binary.LittleEndian, seq)
+	binary.Write(&body, binary.LittleEndian, seq+1)
+	if s.optionalMetadata {
+		// immediate_commit_timestamp in microseconds, without original_commit_timestamp.
+		ts := make([]byte, 8)
+		binary.LittleEndian.PutUint64(ts, uint64(timestamp)*1000000)
+		body.Write(ts[:7])
+		body.WriteByte(0xfc) // transaction_length as a 2-byte length-encoded integer
+		binary.Write(&body, binary.LittleEndian, uint16(800))
+		binary.Write(&body, binary.LittleEndian, uint32(80032)) // immediate_server_version
+	}
+	return body.Bytes()
+}
+
+func (s server) query(query string) []byte {
+	var status bytes.Buffer
+	status.WriteByte(0) // Q_FLAGS2_CODE
+	binary.Write(&status, binary.LittleEndian, uint32(0))
+	status.WriteByte(1) // Q_SQL_MODE_CODE
+	binary.Write(&status, binary.LittleEndian, uint64(0x40000000))
+	status.WriteByte(6) // Q_CATALOG_NZ_CODE
+	status.WriteByte(3)
+	status.WriteString("std")
+	status.WriteByte(4) // Q_CHARSET_CODE
+	binary.Write(&status, binary.LittleEndian, [3]uint16{33, 33, 8})
+	status.WriteByte(5) // Q_TIME_ZONE_CODE
+	status.WriteByte(6)
+	status.WriteString("+00:00")
+	if s.optionalMetadata {
+		status.WriteByte(18) // Q_DEFAULT_COLLATION_FOR_UTF8MB4
+		binary.Write(&status, binary.LittleEndian, uint16(255))
+	}
+
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint32(threadID))
+	binary.Write(&body, binary.LittleEndian, uint32(0)) // exec time
+	body.WriteByte(0)                                   // the default database is empty for BEGIN
+	binary.Write(&body, binary.LittleEndian, uint16(0)) // error code
+	binary.Write(&body, binary.LittleEndian, uint16(status.Len()))
+	body.Write(status.Bytes())
+	body.WriteByte(0)
+	body.WriteString(query)
+	return body.Bytes()
+}
+
+// columns of data_test.tbl_types in capture.sql, with the column types and metadata in table map events.
+var columns = []struct {
+	columnType byte
+	meta       []byte
+}{
+	{1, nil},               // c_tiny TINYINT
+	{2, nil},               // c_short SMALLINT
+	{9, nil},               // c_int24 MEDIUMINT
+	{3, nil},               // c_long INT
+	{8, nil},               // c_longlong BIGINT
+	{4, []byte{4}},         // c_float FLOAT
+	{5, []byte{8}},         // c_double DOUBLE
+	{246, []byte{20, 6}},   // c_decimal DECIMAL(20, 6): precision and scale
+	{13, nil},              // c_year YEAR
+	{10, nil},              // c_date DATE
+	{19, []byte{3}},        // c_time TIME(3): TIME2 with fsp
+	{18, []byte{6}},        // c_datetime DATETIME(6): DATETIME2 with fsp
+	{17, []byte{0}},        // c_timestamp TIMESTAMP: TIMESTAMP2 with fsp
+	{254, []byte{254, 30}}, // c_char CHAR(10) utf8: real type and byte length
+	{15, []byte{0x84, 3}},  // c_varchar VARCHAR(300) utf8: little-endian byte length 900
+	{252, []byte{2}},       // c_blob BLOB: length bytes
+	{252, []byte{2}},       // c_text TEXT: length bytes
+	{16, []byte{2, 1}},     // c_bit BIT(10): bits % 8 and bits / 8
+	{254, []byte{247, 1}},  // c_enum ENUM: real type ENUM and pack length
+	{254, []byte{248, 1}},  // c_set SET: real type SET and pack length
+	{3, nil},               // c_null INT
+}
+
+func (s server) tableMap() []byte {
+	var body bytes.Buffer
+	body.Write([]byte{tableID, 0, 0, 0, 0, 0})
+	binary.Write(&body, binary.LittleEndian, uint16(1)) // TM_BIT_LEN_EXACT_F
+	body.WriteByte(9)
+	body.WriteString("data_test\x00")
+	body.WriteByte(9)
+	body.WriteString("tbl_types\x00")
+	body.WriteByte(byte(len(columns)))
+	var meta bytes.Buffer
+	for _, column := range columns {
+		body.WriteByte(column.columnType)
+		meta.Write(column.meta)
+	}
+	body.WriteByte(byte(meta.Len()))
+	body.Write(meta.Bytes())
+	body.Write([]byte{0xff, 0xff, 0x1f}) // all the columns are nullable
+	if s.optionalMetadata {
+		// binlog_row_metadata=MINIMAL: SIGNEDNESS of the 8 numeric columns, all signed, and DEFAULT_CHARSET utf8_general_ci.
+		body.Write([]byte{1, 1, 0x00})
+		body.Write([]byte{2, 1, 33})
+	}
+	return body.Bytes()
+}
+
+func rowsEvent(eventType byte, rows [][]interface{}) []byte {
+	var body bytes.Buffer
+	body.Write([]byte{tableID, 0, 0, 0, 0, 0})
+	binary.Write(&body, binary.LittleEndian, uint16(1)) // STMT_END_F
+	binary.Write(&body, binary.LittleEndian, uint16(2)) // the extra data length includes itself
+	body.WriteByte(byte(len(columns)))
+	body.Write([]byte{0xff, 0xff, 0x1f})
+	if eventType == updateRowsEventV2 {
+		body.Write([]byte{0xff, 0xff, 0x1f})
+	}
+	for _, row := range rows {
+		body.Write(rowImage(row))
+	}
+	return body.Bytes()
+}
+
+// decimalValue is the integral and fractional digits of a DECIMAL(20, 6).
+type decimalValue struct {
+	integral, fractional string
+	negative             bool
+}
+
+// timeValue is a TIME(3) value.
+type timeValue struct {
+	negative                   bool
+	hour, minute, second, msec int64
+}
+
+// datetimeValue is a DATETIME(6) value.
+type datetimeValue struct {
+	year, month, day, hour, minute, second, usec int64
+}
+
+type dateValue struct{ year, month, day int64 }
+type yearValue int64
+type bitValue uint16
+type blobValue string
+
+// enumValue and setValue are the packed index and bitmap.
+type enumValue byte
+type setValue byte
+
+func insertedRow() []interface{} {
+	return []interface{}{
+		int8(-128), int16(-32768), int24(-8388608), int32(-2147483648), int64(math.MinInt64),
+		float32(1.5), float64(-2.25), decimalValue{"12345678901234", "567890", true},
+		yearValue(2026), dateValue{2026, 10, 18},
+		timeValue{true, 12, 34, 56, 789}, datetimeValue{2026, 10, 18, 12, 34, 56, 123456}, uint32(timestamp),
+		"char", strings.Repeat("v", 300), blobValue("blob"), blobValue("text"), bitValue(682),
+		enumValue(2), setValue(5), nil,
+	}
+}
+
+type int24 int32
+
+func rowImage(row []interface{}) []byte {
+	var image bytes.Buffer
+	nulls := make([]byte, (len(row)+7)/8)
+	for i, value := range row {
+		if value == nil {
+			nulls[i/8] |= 1 << uint(i%8)
+		}
+	}
+	image.Write(nulls)
+	le := binary.LittleEndian
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+		case int8, int16, int32, int64, float32, float64:
+			binary.Write(&image, le, v)
+		case int24:
+			image.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16)})
+		case decimalValue:
+			image.Write(decimalBinary(v))
+		case yearValue:
+			image.WriteByte(byte(v - 1900))
+		case dateValue:
+			n := v.day + v.month*32 + v.year*16*32
+			image.Write([]byte{byte(n), byte(n >> 8), byte(n >> 16)})
+		case timeValue:
+			image.Write(time2Binary(v))
+		case datetimeValue:
+			image.Write(datetime2Binary(v))
+		case uint32:
+			binary.Write(&image, binary.BigEndian, v)
+		case string:
+			if columns[i].columnType == 15 {
+				binary.Write(&image, le, uint16(len(v)))
+			} else {
+				image.WriteByte(byte(len(v)))
+			}
+			image.WriteString(v)
+		case blobValue:
+			binary.Write(&image, le, uint16(len(v)))
+			image.WriteString(string(v))
+		case bitValue:
+			binary.Write(&image, binary.BigEndian, uint16(v))
+		case enumValue:
+			image.WriteByte(byte(v))
+		case setValue:
+			image.WriteByte(byte(v))
+		default:
+			panic("unknown value")
+		}
+	}
+	return image.Bytes()
+}
+
+// decimalBinary follows decimal2bin of strings/decimal.c for DECIMAL(20, 6):
+// 5 leftover integral digits in 3 bytes, 9 integral digits in 4 bytes and 6 fractional digits in 3 bytes.
+func decimalBinary(v decimalValue) []byte {
+	integral := strings.Repeat("0", 14-len(v.integral)) + v.integral
+	var buf bytes.Buffer
+	putBE := func(digits string, size int) {
+		var n uint64
+		for _, c := range digits {
+			n = n*10 + uint64(c-'0')
+		}
+		for i := size - 1; i >= 0; i-- {
+			buf.WriteByte(byte(n >> (8 * uint(i))))
+		}
+	}
+	putBE(integral[:5], 3)
+	putBE(integral[5:], 4)
+	putBE(v.fractional, 3)
+	b := buf.Bytes()
+	if v.negative {
+		for i := range b {
+			b[i] = ^b[i]
+		}
+	}
+	b[0] ^= 0x80
+	return b
+}
+
+// time2Binary follows my_time_packed_to_binary of sql-common/my_time.c for TIME(3),
+// where the integral part is the floor and the fractional part is truncated towards zero.
+func time2Binary(v timeValue) []byte {
+	hms := v.hour<<12 | v.minute<<6 | v.second
+	packed := hms<<24 + v.msec*1000
+	if v.negative {
+		packed = -packed
+	}
+	intPart := (packed >> 24) + 0x800000
+	fracPart := (packed % (1 << 24)) / 100
+	return []byte{byte(intPart >> 16), byte(intPart >> 8), byte(intPart), byte(fracPart >> 8), byte(fracPart)}
+}
+
+// datetime2Binary follows my_datetime_packed_to_binary of sql-common/my_time.c for DATETIME(6).
+func datetime2Binary(v datetimeValue) []byte {
+	ymd := (v.year*13+v.month)<<5 | v.day
+	hms := v.hour<<12 | v.minute<<6 | v.second
+	intPart := ymd<<17 | hms + 0x8000000000
+	return []byte{byte(intPart >> 32), byte(intPart >> 24), byte(intPart >> 16), byte(intPart >> 8), byte(intPart),
+		byte(v.usec >> 16), byte(v.usec >> 8), byte(v.usec)}
+}
@@ -0,0 +1,100 @@
+package msops
+
+// Transaction represents one open InnoDB transaction and the process owning it.
+//
+// Field specification can be found at https://dev.mysql.com/doc/refman/5.6/en/innodb-trx-table.html
+type Transaction struct {
+	TrxID          string
+	State          string
+	Started        string
+	AgeSeconds     int
+	RowsLocked     int
+	RowsModified   int
+	LockStructs    int
+	TablesLocked   int
+	Weight         int
+	IsolationLevel string
+
+	// Process is the owner of the transaction. Its 'Command' is "Sleep" if the transaction is idle.
+	Process Process
+}
+
+// IdleInTransaction reports whether the owner of the transaction is sleeping with the transaction open.
+func (trx Transaction) IdleInTransaction() bool {
+	return trx.Process.Command == "Sleep"
+}
+
+// HistoryListReport correlates the InnoDB history list length with the open transactions.
+//
+// Purge can't remove the undo logs newer than the read view of the oldest transaction,
+// so Oldest is the most likely one bloating the undo logs when HistoryListLength keeps growing.
+type HistoryListReport struct {
+	HistoryListLength int
+	Transactions      []Transaction
+	Oldest            *Transaction
+}
+
+const openTransactionsQuery = "SELECT trx.*, TIMESTAMPDIFF(SECOND, trx.trx_started, NOW()) AS trx_age, " +
+	"p.ID, p.USER, p.HOST, p.DB, p.COMMAND, p.TIME, p.STATE, p.INFO " +
+	"FROM information_schema.INNODB_TRX trx " +
+	"LEFT JOIN information_schema.PROCESSLIST p ON p.ID = trx.trx_mysql_thread_id " +
+	"ORDER BY trx.trx_started"
+
+// GetOpenTransactions returns the open InnoDB transactions of the endpoint, from the oldest to the newest.
+//
+// The number of rows modified approximates the undo records generated by the transaction,
+// as InnoDB doesn't expose the undo size per transaction.
+func GetOpenTransactions(endpoint string) ([]Transaction, error) {
+	var dataSet []map[string]string
+	var err error
+	if dataSet, err = readDataSet(endpoint, openTransactionsQuery); err != nil {
+		return nil, err
+	}
+	transactions := make([]Transaction, 0, len(dataSet))
+	for _, row := range dataSet {
+		transactions = append(transactions, Transaction{
+			TrxID:          row["trx_id"],
+			State:          row["trx_state"],
+			Started:        row["trx_started"],
+			AgeSeconds:     getInt(row["trx_age"]),
+			RowsLocked:     getInt(row["trx_rows_locked"]),
+			RowsModified:   getInt(row["trx_rows_modified"]),
+			LockStructs:    getInt(row["trx_lock_structs"]),
+			TablesLocked:   getInt(row["trx_tables_locked"]),
+			Weight:         getInt(row["trx_weight"]),
+			IsolationLevel: row["trx_isolation_level"],
+			Process: Process{
+				ID:         getInt(row["ID"]),
+				User:       row["USER"],
+				Host:       row["HOST"],
+				DB:         row["DB"],
+				Command:    row["COMMAND"],
+				Time:       getInt(row["TIME"]),
+				State:      row["STATE"],
+				Info:       row["INFO"],
+				TrxState:   row["trx_state"],
+				TrxStarted: row["trx_started"],
+			},
+		})
+	}
+	return transactions, nil
+}
+
+// GetHistoryListReport returns the history list length from "SHOW engine InnoDB STATUS"
+// along with the open transactions of the endpoint.
+func GetHistoryListReport(endpoint string) (HistoryListReport, error) {
+	var report HistoryListReport
+	var innodbStatus InnoDBStatus
+	var err error
+	if innodbStatus, err = GetInnoDBStatus(endpoint); err != nil {
+		return report, err
+	}
+	if report.Transactions, err = GetOpenTransactions(endpoint); err != nil {
+		return report, err
+	}
+	report.HistoryListLength = innodbStatus.InnodbHistoryListLength
+	if len(report.Transactions) > 0 {
+		report.Oldest = &report.Transactions[0]
+	}
+	return report, nil
+}
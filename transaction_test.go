@@ -0,0 +1,31 @@
+package msops
+
+import "testing"
+
+func TestGetOpenTransactions(t *testing.T) {
+	if _, err := GetOpenTransactions(badEndpoint); err == nil {
+		t.Error("Get badEndpoint open transactions should cause error")
+	}
+	tx, err := connectionPool[testEndpoint1].connection.Begin()
+	if err != nil {
+		t.Fatalf("Test GetOpenTransactions begin error: %s", err.Error())
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("UPDATE data_test.tbl_test SET name = 'idle' WHERE id = 1"); err != nil {
+		t.Fatalf("Test GetOpenTransactions update error: %s", err.Error())
+	}
+
+	if transactions, err := GetOpenTransactions(testEndpoint1); err != nil {
+		t.Errorf("Test GetOpenTransactions error: %s", err.Error())
+	} else if len(transactions) != 1 {
+		t.Errorf("Test GetOpenTransactions failed: actual %d transactions, expected 1", len(transactions))
+	} else if trx := transactions[0]; trx.RowsModified != 1 || !trx.IdleInTransaction() || trx.Process.User != testDBAUser {
+		t.Errorf("Test GetOpenTransactions failed: unexpected transaction %+v", trx)
+	}
+
+	if report, err := GetHistoryListReport(testEndpoint1); err != nil {
+		t.Errorf("Test GetHistoryListReport error: %s", err.Error())
+	} else if report.Oldest == nil || report.Oldest.RowsModified != 1 {
+		t.Errorf("Test GetHistoryListReport failed: unexpected oldest transaction %+v", report.Oldest)
+	}
+}
@@ -0,0 +1,339 @@
+package msops
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// variableType is the type of the value of a system variable.
+type variableType int
+
+const (
+	variableString variableType = iota
+	variableBool
+	variableInt
+	variableFloat
+	variableEnum
+)
+
+// variableScope is where a system variable can be set.
+type variableScope int
+
+const (
+	scopeBoth variableScope = iota
+	scopeGlobal
+	scopeSession
+)
+
+// variableInfo is the metadata of a system variable.
+type variableInfo struct {
+	Type    variableType
+	Scope   variableScope
+	Dynamic bool
+
+	// DynamicSince is the version since which the variable is dynamic, which is zero if always dynamic.
+	DynamicSince serverVersion
+
+	// Min and Max are the range of numeric values, which are not checked if both are 0.
+	Min float64
+	Max float64
+
+	// Values are the permitted values of enum variables.
+	Values []string
+}
+
+// dynamic reports whether the variable can be set at runtime on the version.
+func (info variableInfo) dynamic(version serverVersion) bool {
+	return info.Dynamic && version.compare(info.DynamicSince) >= 0
+}
+
+const maxUint32 = 4294967295
+
+// variableCatalog is the bundled metadata of the common system variables. The server doesn't expose
+// the type, scope and dynamic of the variables, and performance_schema.variables_info of MySQL 8.0
+// only overrides the range of the numeric ones.
+//
+// Variable specification can be found at https://dev.mysql.com/doc/refman/5.7/en/server-system-variables.html
+var variableCatalog = map[string]variableInfo{
+	"autocommit":                     {Type: variableBool, Dynamic: true},
+	"binlog_cache_size":              {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 4096, Max: math.MaxUint64},
+	"binlog_expire_logs_seconds":     {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: maxUint32},
+	"binlog_format":                  {Type: variableEnum, Dynamic: true, Values: []string{"ROW", "STATEMENT", "MIXED"}},
+	"binlog_row_image":               {Type: variableEnum, Dynamic: true, Values: []string{"FULL", "MINIMAL", "NOBLOB"}},
+	"enforce_gtid_consistency":       {Type: variableEnum, Scope: scopeGlobal, Dynamic: true, DynamicSince: serverVersion{5, 7, 6}, Values: []string{"OFF", "ON", "WARN"}},
+	"expire_logs_days":               {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: 99},
+	"general_log":                    {Type: variableBool, Scope: scopeGlobal, Dynamic: true},
+	"gtid_mode":                      {Type: variableEnum, Scope: scopeGlobal, Dynamic: true, DynamicSince: serverVersion{5, 7, 6}, Values: []string{"OFF", "OFF_PERMISSIVE", "ON_PERMISSIVE", "ON"}},
+	"innodb_buffer_pool_size":        {Type: variableInt, Scope: scopeGlobal, Dynamic: true, DynamicSince: serverVersion{5, 7, 5}, Min: 5242880, Max: math.MaxUint64},
+	"innodb_flush_log_at_trx_commit": {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: 2},
+	"innodb_io_capacity":             {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 100, Max: math.MaxUint64},
+	"innodb_lock_wait_timeout":       {Type: variableInt, Dynamic: true, Min: 1, Max: 1073741824},
+	"interactive_timeout":            {Type: variableInt, Dynamic: true, Min: 1, Max: 31536000},
+	"lock_wait_timeout":              {Type: variableInt, Dynamic: true, Min: 1, Max: 31536000},
+	"log_queries_not_using_indexes":  {Type: variableBool, Scope: scopeGlobal, Dynamic: true},
+	"long_query_time":                {Type: variableFloat, Dynamic: true, Max: 31536000},
+	"max_allowed_packet":             {Type: variableInt, Dynamic: true, Min: 1024, Max: 1073741824},
+	"max_binlog_size":                {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 4096, Max: 1073741824},
+	"max_connections":                {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 1, Max: 100000},
+	"max_heap_table_size":            {Type: variableInt, Dynamic: true, Min: 16384, Max: math.MaxUint64},
+	"net_read_timeout":               {Type: variableInt, Dynamic: true, Min: 1, Max: 31536000},
+	"net_write_timeout":              {Type: variableInt, Dynamic: true, Min: 1, Max: 31536000},
+	"read_only":                      {Type: variableBool, Scope: scopeGlobal, Dynamic: true},
+	"slave_net_timeout":              {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 1, Max: 31536000},
+	"slave_parallel_workers":         {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: 1024},
+	"slow_query_log":                 {Type: variableBool, Scope: scopeGlobal, Dynamic: true},
+	"sql_mode":                       {Type: variableString, Dynamic: true},
+	"sql_slave_skip_counter":         {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: maxUint32},
+	"super_read_only":                {Type: variableBool, Scope: scopeGlobal, Dynamic: true},
+	"sync_binlog":                    {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: maxUint32},
+	"table_open_cache":               {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Min: 1, Max: 524288},
+	"thread_cache_size":              {Type: variableInt, Scope: scopeGlobal, Dynamic: true, Max: 16384},
+	"tmp_table_size":                 {Type: variableInt, Dynamic: true, Min: 1024, Max: math.MaxUint64},
+	"wait_timeout":                   {Type: variableInt, Dynamic: true, Min: 1, Max: 31536000},
+
+	// The variables which can't be changed at runtime.
+	"back_log":                     {Type: variableInt, Scope: scopeGlobal},
+	"basedir":                      {Scope: scopeGlobal},
+	"bind_address":                 {Scope: scopeGlobal},
+	"datadir":                      {Scope: scopeGlobal},
+	"hostname":                     {Scope: scopeGlobal},
+	"innodb_autoinc_lock_mode":     {Type: variableInt, Scope: scopeGlobal},
+	"innodb_buffer_pool_instances": {Type: variableInt, Scope: scopeGlobal},
+	"innodb_data_file_path":        {Scope: scopeGlobal},
+	"innodb_data_home_dir":         {Scope: scopeGlobal},
+	"innodb_log_file_size":         {Type: variableInt, Scope: scopeGlobal},
+	"innodb_log_files_in_group":    {Type: variableInt, Scope: scopeGlobal},
+	"innodb_page_size":             {Type: variableInt, Scope: scopeGlobal},
+	"innodb_read_io_threads":       {Type: variableInt, Scope: scopeGlobal},
+	"innodb_write_io_threads":      {Type: variableInt, Scope: scopeGlobal},
+	"log_bin":                      {Type: variableBool, Scope: scopeGlobal},
+	"log_bin_basename":             {Scope: scopeGlobal},
+	"lower_case_table_names":       {Type: variableInt, Scope: scopeGlobal},
+	"open_files_limit":             {Type: variableInt, Scope: scopeGlobal},
+	"performance_schema":           {Type: variableBool, Scope: scopeGlobal},
+	"port":                         {Type: variableInt, Scope: scopeGlobal},
+	"relay_log":                    {Scope: scopeGlobal},
+	"server_uuid":                  {Scope: scopeGlobal},
+	"skip_name_resolve":            {Type: variableBool, Scope: scopeGlobal},
+	"socket":                       {Scope: scopeGlobal},
+	"table_open_cache_instances":   {Type: variableInt, Scope: scopeGlobal},
+	"thread_handling":              {Scope: scopeGlobal},
+	"version":                      {Scope: scopeGlobal},
+
+	// The variables which can only be set in session.
+	"gtid_next":        {Scope: scopeSession, Dynamic: true},
+	"insert_id":        {Type: variableInt, Scope: scopeSession, Dynamic: true},
+	"last_insert_id":   {Type: variableInt, Scope: scopeSession, Dynamic: true},
+	"pseudo_thread_id": {Type: variableInt, Scope: scopeSession, Dynamic: true},
+	"sql_log_bin":      {Type: variableBool, Scope: scopeSession, Dynamic: true},
+	"timestamp":        {Type: variableFloat, Scope: scopeSession, Dynamic: true},
+}
+
+var (
+	errVariableUnknown    = errors.New("the variable is unknown")
+	errVariableUnchecked  = errors.New("the variable is not in the catalog, and only numeric or boolean values or Unvalidated are accepted")
+	errVariableNotDynamic = errors.New("the variable can't be set at runtime")
+	errVariableSession    = errors.New("the variable can only be set in session")
+	errVariableType       = errors.New("the value type doesn't match the variable")
+	errVariableRange      = errors.New("the value is out of the range of the variable")
+)
+
+// Unvalidated wraps the value of a variable not in the bundled catalog of msops, whose type, scope and dynamic
+// are unknown. Such a variable is rejected by SetGlobalVariable unless its current value is numeric or boolean,
+// or the value is wrapped by Unvalidated, which passes it to the server as is.
+//
+// The value of a cataloged variable is validated as the plain string.
+type Unvalidated string
+
+// isStaticVariable reports whether the variable is known not to be dynamic on the version.
+func isStaticVariable(name string, version serverVersion) bool {
+	info, exists := variableCatalog[name]
+	return exists && !info.dynamic(version)
+}
+
+// variableAssignment is a validated "SET GLOBAL" of one variable.
+type variableAssignment struct {
+	name    string
+	arg     interface{}
+	current string
+
+	// restore is current converted to the type of the variable, which restores the variable by "SET GLOBAL".
+	restore interface{}
+}
+
+// prepareGlobalVariable checks the existence of the variable on the server, its scope and dynamic in variableCatalog,
+// and converts value to the type of the variable after validating its range.
+// The range is read from performance_schema.variables_info on MySQL 8.0.
+// The non-dynamic variables are allowed if allowStatic is true, e.g. for "SET PERSIST_ONLY".
+//
+// The type of an uncataloged variable is inferred from its current value if it's numeric or boolean,
+// otherwise value must be Unvalidated.
+func prepareGlobalVariable(endpoint, key string, value interface{}, version serverVersion, allowStatic bool) (variableAssignment, error) {
+	assignment := variableAssignment{name: strings.ToLower(key)}
+	raw, unvalidated := value.(Unvalidated)
+	if unvalidated {
+		value = string(raw)
+	}
+	if !globalKeyExp.MatchString(key) {
+		return assignment, errKeyInvalid
+	}
+	variables, err := GetGlobalVariables(endpoint, assignment.name)
+	if err != nil {
+		return assignment, err
+	}
+	info, cataloged := variableCatalog[assignment.name]
+	current, exists := variables[assignment.name]
+	if !exists {
+		if cataloged && info.Scope == scopeSession {
+			return assignment, errVariableSession
+		}
+		return assignment, errVariableUnknown
+	}
+	assignment.current, assignment.restore = current, current
+	if cataloged && !allowStatic && !info.dynamic(version) {
+		return assignment, errVariableNotDynamic
+	}
+	if cataloged && info.Scope == scopeSession {
+		return assignment, errVariableSession
+	}
+	if !cataloged {
+		// Infer the type from the current value, and leave the others to the server.
+		if _, e := strconv.ParseInt(current, 10, 64); e == nil {
+			info.Type = variableInt
+		} else if v := strings.ToUpper(current); v == "ON" || v == "OFF" {
+			info.Type = variableBool
+		} else if unvalidated {
+			assignment.arg = value
+			return assignment, nil
+		} else {
+			return assignment, errVariableUnchecked
+		}
+	}
+	if version.atLeast(8, 0, 0) {
+		if dataSet, e := readDataSet(endpoint, "SELECT MIN_VALUE, MAX_VALUE FROM performance_schema.variables_info "+
+			"WHERE VARIABLE_NAME = ?", assignment.name); e == nil && len(dataSet) == 1 {
+			info.Min, info.Max = getFloat(dataSet[0]["MIN_VALUE"]), getFloat(dataSet[0]["MAX_VALUE"])
+		}
+	}
+	// The current value is restored as is if it's out of the range known by msops.
+	if restore, e := info.convert(current); e == nil {
+		assignment.restore = restore
+	}
+	assignment.arg, err = info.convert(value)
+	return assignment, err
+}
+
+// convert validates value and converts it to the argument of "SET GLOBAL".
+func (info variableInfo) convert(value interface{}) (interface{}, error) {
+	var number float64
+	switch v := value.(type) {
+	case bool:
+		if info.Type != variableBool {
+			return nil, errVariableType
+		}
+		return v, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		number, _ = strconv.ParseFloat(fmt.Sprint(v), 64)
+		if info.Type == variableBool && number != 0 && number != 1 {
+			return nil, errVariableRange
+		}
+		if info.Type != variableInt && info.Type != variableFloat && info.Type != variableBool {
+			return nil, errVariableType
+		}
+	case float32, float64:
+		number, _ = strconv.ParseFloat(fmt.Sprint(v), 64)
+		if info.Type != variableFloat {
+			return nil, errVariableType
+		}
+	case string:
+		switch info.Type {
+		case variableBool:
+			switch normalizeVariable(v) {
+			case "ON", "1":
+				return "ON", nil
+			case "OFF", "0":
+				return "OFF", nil
+			}
+			return nil, errVariableType
+		case variableEnum:
+			for _, permitted := range info.Values {
+				if strings.EqualFold(v, permitted) {
+					return permitted, nil
+				}
+			}
+			return nil, errVariableType
+		case variableInt:
+			n, err := strconv.ParseInt(normalizeVariable(v), 10, 64)
+			if err != nil {
+				return nil, errVariableType
+			}
+			value, number = n, float64(n)
+		case variableFloat:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, errVariableType
+			}
+			value, number = f, f
+		default:
+			return v, nil
+		}
+	default:
+		return nil, errVariableType
+	}
+	if (info.Min != 0 || info.Max != 0) && (number < info.Min || number > info.Max) {
+		return nil, errVariableRange
+	}
+	return value, nil
+}
+
+// SetGlobalVariables validates all the variables before setting any of them, and sets them in the order of names.
+// If setting one of them fails, the ones already set are restored to the previous values in the reverse order.
+//
+// The changes are returned with the previous values, and Applied of the restored ones is false.
+func SetGlobalVariables(endpoint string, variables map[string]interface{}) ([]VariableChange, error) {
+	var version serverVersion
+	var err error
+	if _, exists := getInstance(endpoint); !exists {
+		return nil, errNotRegistered
+	}
+	if version, err = getVersion(endpoint); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assignments := make([]variableAssignment, 0, len(names))
+	for _, name := range names {
+		assignment, e := prepareGlobalVariable(endpoint, name, variables[name], version, false)
+		if e != nil {
+			return nil, fmt.Errorf("%s: %s", name, e.Error())
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	changes := make([]VariableChange, 0, len(assignments))
+	for i, assignment := range assignments {
+		change := VariableChange{Name: assignment.name, From: assignment.current, To: fmt.Sprint(assignment.arg)}
+		if change.Error = execute(endpoint, fmt.Sprintf("SET GLOBAL %s=?", assignment.name), assignment.arg); change.Error == nil {
+			change.Applied = !isDryRun(endpoint)
+			changes = append(changes, change)
+			continue
+		}
+		changes = append(changes, change)
+		err = fmt.Errorf("%s: %s", assignment.name, change.Error.Error())
+		for j := i - 1; j >= 0; j-- {
+			if e := execute(endpoint, fmt.Sprintf("SET GLOBAL %s=?", assignments[j].name), assignments[j].restore); e != nil {
+				return changes, fmt.Errorf("%s, and restoring %s failed: %s", err.Error(), assignments[j].name, e.Error())
+			}
+			changes[j].Applied = false
+		}
+		return changes, err
+	}
+	return changes, nil
+}
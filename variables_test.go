@@ -0,0 +1,123 @@
+package msops
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariableInfoConvert(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+		err      error
+	}{
+		{"read_only", "on", "ON", nil},
+		{"read_only", true, true, nil},
+		{"read_only", 2, nil, errVariableRange},
+		{"read_only", "maybe", nil, errVariableType},
+		{"binlog_format", "row", "ROW", nil},
+		{"binlog_format", "BINARY", nil, errVariableType},
+		{"max_connections", 500, 500, nil},
+		{"max_connections", "1000", int64(1000), nil},
+		{"max_connections", 0, nil, errVariableRange},
+		{"max_connections", 1.5, nil, errVariableType},
+		{"max_allowed_packet", "64M", int64(67108864), nil},
+		{"long_query_time", 0.5, 0.5, nil},
+		{"long_query_time", "2", 2.0, nil},
+		{"sql_mode", "STRICT_TRANS_TABLES", "STRICT_TRANS_TABLES", nil},
+		{"sql_mode", 1, nil, errVariableType},
+	}
+	for _, c := range cases {
+		actual, err := variableCatalog[c.name].convert(c.value)
+		if err != c.err || actual != c.expected {
+			t.Errorf("Test variableInfo convert %s=%v failed: actual %v (%v), expected %v (%v)",
+				c.name, c.value, actual, err, c.expected, c.err)
+		}
+	}
+	if !isStaticVariable("innodb_buffer_pool_size", serverVersion{5, 6, 30}) ||
+		isStaticVariable("innodb_buffer_pool_size", serverVersion{5, 7, 5}) {
+		t.Error("Test isStaticVariable failed: innodb_buffer_pool_size should be dynamic since 5.7.5")
+	}
+}
+
+func TestSetGlobalVariableValidation(t *testing.T) {
+	if SetGlobalVariable(testEndpoint1, "no_such_variable", 1) != errVariableUnknown {
+		t.Error("Set unknown global variable should throw errVariableUnknown")
+	}
+	if SetGlobalVariable(testEndpoint1, "port", 3399) != errVariableNotDynamic {
+		t.Error("Set non-dynamic global variable should throw errVariableNotDynamic")
+	}
+	if SetGlobalVariable(testEndpoint1, "max_connections", -1) != errVariableRange {
+		t.Error("Set global variable out of range should throw errVariableRange")
+	}
+	if SetGlobalVariable(testEndpoint1, "binlog_format", 1.5) != errVariableType {
+		t.Error("Set global variable with wrong type should throw errVariableType")
+	}
+	if SetGlobalVariable(testEndpoint1, "expire_logs_days;", 1) != errKeyInvalid {
+		t.Error("Set global variables with invalid key should throw errKeyInvalid")
+	}
+	if SetGlobalVariable(testEndpoint1, "init_connect", "") != errVariableUnchecked {
+		t.Error("Set uncataloged string variable should throw errVariableUnchecked")
+	}
+	if err := SetGlobalVariable(testEndpoint1, "init_connect", Unvalidated("")); err != nil {
+		t.Errorf("Set uncataloged string variable with Unvalidated error: %s", err.Error())
+	}
+	if SetGlobalVariable(testEndpoint1, "binlog_format", Unvalidated("NO_SUCH_FORMAT")) != errVariableType {
+		t.Error("Set cataloged global variable with wrong Unvalidated value should throw errVariableType")
+	}
+}
+
+func TestSetGlobalVariables(t *testing.T) {
+	if _, err := SetGlobalVariables(unregisteredEndpoint, nil); err != errNotRegistered {
+		t.Error("Test SetGlobalVariables unregisteredEndpoint error: should return errNotRegistered")
+	}
+	origin, err := GetGlobalVariables(testEndpoint3, "%timeout")
+	if err != nil {
+		t.Fatalf("Test SetGlobalVariables GetGlobalVariables error: %s", err.Error())
+	}
+	defer SetGlobalVariable(testEndpoint3, "net_read_timeout", origin["net_read_timeout"])
+	defer SetGlobalVariable(testEndpoint3, "net_write_timeout", origin["net_write_timeout"])
+
+	if _, err = SetGlobalVariables(testEndpoint3, map[string]interface{}{
+		"net_read_timeout": 100,
+		"port":             3399,
+	}); err == nil {
+		t.Error("Test SetGlobalVariables with non-dynamic variable should cause error")
+	} else if current, _ := GetGlobalVariables(testEndpoint3, "net_read_timeout"); current["net_read_timeout"] != origin["net_read_timeout"] {
+		t.Error("Test SetGlobalVariables failed: nothing should be set if validation fails")
+	}
+
+	changes, err := SetGlobalVariables(testEndpoint3, map[string]interface{}{
+		"net_read_timeout":  100,
+		"net_write_timeout": 200,
+	})
+	if err != nil {
+		t.Fatalf("Test SetGlobalVariables error: %s", err.Error())
+	}
+	if len(changes) != 2 || changes[0].Name != "net_read_timeout" || changes[0].From != origin["net_read_timeout"] ||
+		changes[1].To != "200" || !changes[1].Applied {
+		t.Errorf("Test SetGlobalVariables failed: actual changes %+v", changes)
+	}
+
+	// The float variable set before the failed one is restored.
+	slowLog, err := GetGlobalVariables(testEndpoint3, "long_query_time")
+	if err != nil {
+		t.Fatalf("Test SetGlobalVariables GetGlobalVariables error: %s", err.Error())
+	}
+	defer SetGlobalVariable(testEndpoint3, "long_query_time", slowLog["long_query_time"])
+	changes, err = SetGlobalVariables(testEndpoint3, map[string]interface{}{
+		"long_query_time": 2.5,
+		"sql_mode":        "NOT_A_SQL_MODE",
+	})
+	if err == nil {
+		t.Error("Test SetGlobalVariables with invalid sql_mode should cause error")
+	} else if strings.Contains(err.Error(), "restoring") {
+		t.Errorf("Test SetGlobalVariables rollback error: %s", err.Error())
+	} else if len(changes) != 2 || changes[0].Applied {
+		t.Errorf("Test SetGlobalVariables rollback failed: actual changes %+v", changes)
+	} else if current, _ := GetGlobalVariables(testEndpoint3, "long_query_time"); current["long_query_time"] != slowLog["long_query_time"] {
+		t.Errorf("Test SetGlobalVariables rollback failed: actual long_query_time %s, expected %s",
+			current["long_query_time"], slowLog["long_query_time"])
+	}
+}
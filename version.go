@@ -0,0 +1,72 @@
+package msops
+
+import (
+	"strconv"
+	"strings"
+)
+
+// serverVersion represents the version of a MySQL server, e.g. 5.6.30.
+type serverVersion struct {
+	major int
+	minor int
+	patch int
+}
+
+// parseVersion parses the value of global variable 'version', e.g. "5.6.30-log".
+func parseVersion(s string) serverVersion {
+	if idx := strings.IndexAny(s, "-+ "); idx >= 0 {
+		s = s[:idx]
+	}
+	parts := strings.SplitN(s, ".", 3)
+	var v serverVersion
+	if len(parts) > 0 {
+		v.major, _ = strconv.Atoi(parts[0])
+	}
+	if len(parts) > 1 {
+		v.minor, _ = strconv.Atoi(parts[1])
+	}
+	if len(parts) > 2 {
+		v.patch, _ = strconv.Atoi(parts[2])
+	}
+	return v
+}
+
+// compare returns -1, 0 or 1 if v is older than, the same as or newer than o.
+func (v serverVersion) compare(o serverVersion) int {
+	switch {
+	case v.major != o.major:
+		return compareInt(v.major, o.major)
+	case v.minor != o.minor:
+		return compareInt(v.minor, o.minor)
+	default:
+		return compareInt(v.patch, o.patch)
+	}
+}
+
+// atLeast reports whether v is major.minor.patch or newer.
+func (v serverVersion) atLeast(major, minor, patch int) bool {
+	return v.compare(serverVersion{major, minor, patch}) >= 0
+}
+
+func (v serverVersion) String() string {
+	return strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor) + "." + strconv.Itoa(v.patch)
+}
+
+// getVersion returns the version of the endpoint.
+func getVersion(endpoint string) (serverVersion, error) {
+	variables, err := GetGlobalVariables(endpoint, "version")
+	if err != nil {
+		return serverVersion{}, err
+	}
+	return parseVersion(variables["version"]), nil
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
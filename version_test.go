@@ -0,0 +1,31 @@
+package msops
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	versions := map[string]serverVersion{
+		"5.6.30-log":               {5, 6, 30},
+		"8.0.32":                   {8, 0, 32},
+		"5.7.41-44-log":            {5, 7, 41},
+		"10.6.12-MariaDB-1:10.6.1": {10, 6, 12},
+	}
+	for s, expected := range versions {
+		if actual := parseVersion(s); actual != expected {
+			t.Errorf("Test parseVersion %s failed: actual %s, expected %s", s, actual, expected)
+		}
+	}
+	if !parseVersion("5.7.9").atLeast(5, 6, 30) || parseVersion("5.6.30").atLeast(5, 7, 0) {
+		t.Error("Test serverVersion atLeast failed")
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	if v, err := getVersion(testEndpoint1); err != nil {
+		t.Errorf("Test getVersion error: %s", err.Error())
+	} else if !v.atLeast(5, 6, 0) {
+		t.Errorf("Test getVersion failed: actual %s, expected 5.6 or newer", v)
+	}
+	if _, err := getVersion(unregisteredEndpoint); err != errNotRegistered {
+		t.Error("Test getVersion unregisteredEndpoint error: should return errNotRegistered")
+	}
+}